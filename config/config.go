@@ -3,6 +3,7 @@ package config
 import (
 	"log" // log: package for logging
 	"os"  // os: package for operating system functionality
+	"strconv"
 
 	"github.com/joho/godotenv" // godotenv: package for reading .env files
 )
@@ -19,3 +20,11 @@ func GetEnv(key, fallback string) string { // GetEnv: function to get environmen
 	}
 	return fallback // return fallback
 }
+
+func GetEnvInt(key string, fallback int) int { // GetEnvInt: function to get an integer environment variable
+	value, err := strconv.Atoi(GetEnv(key, "")) // strconv.Atoi: function to parse an integer
+	if err != nil {
+		return fallback // return fallback when unset or invalid
+	}
+	return value // return value
+}
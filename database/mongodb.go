@@ -71,7 +71,7 @@ func connect(clientOptions *options.ClientOptions) (*mongo.Client, error) {
 
 // CreateTextIndex creates a text index on the specified fields of a collection.
 func CreateTextIndex(collectionName string, fields []string) {
-	collection := MongoClient.Database("gogemini").Collection(collectionName)
+	collection := MongoClient.Database(GetDatabaseName()).Collection(collectionName)
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -95,18 +95,131 @@ func CreateTextIndex(collectionName string, fields []string) {
 	}
 }
 
+// CreateIndex creates the given index on a collection.
+func CreateIndex(collectionName string, indexModel mongo.IndexModel) {
+	collection := MongoClient.Database(GetDatabaseName()).Collection(collectionName)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	indexOptions := options.CreateIndexes().SetMaxTime(10 * time.Second)
+	createdIndexName, err := collection.Indexes().CreateOne(ctx, indexModel, indexOptions)
+	if err != nil {
+		log.Fatalf("Failed to create index on %s collection: %v", collectionName, err)
+	} else if createdIndexName != "" {
+		log.Printf("Index %s on %s collection created or verified successfully", createdIndexName, collectionName)
+	}
+}
+
+// slugIndexes returns the indexes used to look documents up by current or previous slug.
+// Documents created before slugs existed have no slug field, so uniqueness only applies to string slugs.
+func slugIndexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "slug", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"slug": bson.M{"$type": "string"}}),
+		},
+		{Keys: bson.D{{Key: "previous_slugs", Value: 1}}},
+	}
+}
+
 // CreateIndexesForCollections initializes indexes for all collections.
 func CreateIndexesForCollections() {
 	// Define the collections and their text index fields
 	collections := map[string][]string{
-
-		"activity_category": {"name"},
+		"article_category": {"name"},
 	}
 
 	// Create indexes for each collection
 	for collectionName, fields := range collections {
 		CreateTextIndex(collectionName, fields)
 	}
+
+	// Define the remaining indexes for each collection
+	indexes := map[string][]mongo.IndexModel{
+		"article_category": slugIndexes(),
+		"article_content": append(slugIndexes(),
+			mongo.IndexModel{Keys: bson.D{{Key: "tags", Value: 1}}},
+			mongo.IndexModel{Keys: bson.D{{Key: "enrichment_status", Value: 1}}},
+		),
+		"article_locks": {
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"refresh_tokens": {
+			{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "family_id", Value: 1}}},
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"password_resets": {
+			{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"audit_logs": {
+			{Keys: bson.D{{Key: "created_at", Value: -1}}},
+			{Keys: bson.D{{Key: "action", Value: 1}, {Key: "created_at", Value: -1}}},
+			{Keys: bson.D{{Key: "target_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		},
+		"invitations": {
+			{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			// One pending invitation per address
+			{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": "pending"})},
+			{Keys: bson.D{{Key: "created_at", Value: -1}}},
+		},
+		"passkeys": {
+			{Keys: bson.D{{Key: "credential_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		},
+		"webauthn_ceremonies": {
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"oidc_states": {
+			{Keys: bson.D{{Key: "state_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"oidc_login_codes": {
+			{Keys: bson.D{{Key: "code_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"users": {
+			// One account per identity at a provider
+			{Keys: bson.D{{Key: "oidc_issuer", Value: 1}, {Key: "oidc_subject", Value: 1}}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"oidc_subject": bson.M{"$exists": true}})},
+		},
+		"revoked_tokens": {
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"mfa_attempts": {
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"idempotency_keys": {
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"tags": {
+			{Keys: bson.D{{Key: "slug", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "usage_count", Value: -1}}},
+		},
+	}
+
+	for collectionName, indexModels := range indexes {
+		for _, indexModel := range indexModels {
+			CreateIndex(collectionName, indexModel)
+		}
+	}
+}
+
+// WithTransaction runs fn inside a MongoDB transaction, committing on success and aborting on error.
+func WithTransaction(ctx context.Context, fn func(sessCtx mongo.SessionContext) error) error {
+	session, err := MongoClient.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
 }
 
 // GetMongoClient provides access to the MongoDB client instance.
@@ -4,11 +4,23 @@ module myfiberproject
 
 go 1.22.0
 
-require github.com/patrickmn/go-cache v2.1.0+incompatible
+require (
+	github.com/go-webauthn/webauthn v0.11.1
+	github.com/microcosm-cc/bluemonday v1.0.27
+	github.com/patrickmn/go-cache v2.1.0+incompatible
+	github.com/yuin/goldmark v1.7.8
+)
 
 require (
+	github.com/aymerick/douceur v0.2.0 // indirect
+	github.com/fxamacker/cbor/v2 v2.7.0 // indirect
+	github.com/go-webauthn/x v0.1.12 // indirect
+	github.com/golang-jwt/jwt/v5 v5.2.1 // indirect
 	github.com/google/go-cmp v0.6.0 // indirect
-	github.com/stretchr/testify v1.9.0 // indirect
+	github.com/google/go-tpm v0.9.1 // indirect
+	github.com/gorilla/css v1.0.1 // indirect
+	github.com/mitchellh/mapstructure v1.5.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
 )
 
 require (
@@ -38,8 +50,8 @@ require (
 	github.com/youmark/pkcs8 v0.0.0-20181117223130-1be2e3e5546d // indirect
 	go.mongodb.org/mongo-driver v1.15.0
 	golang.org/x/crypto v0.29.0
-	golang.org/x/net v0.31.0 // indirect
+	golang.org/x/net v0.31.0
 	golang.org/x/sync v0.9.0 // indirect
 	golang.org/x/sys v0.27.0 // indirect
-	golang.org/x/text v0.20.0 // indirect
+	golang.org/x/text v0.20.0
 )
@@ -36,7 +36,8 @@ func SeedAdmin() (*models.User, error) {
 		return nil, fmt.Errorf("one or more required admin seed environment variables are not set")
 	}
 
-	// Define the admin user
+	// Define the admin user; the seeded address counts as verified
+	now := time.Now()
 	admin := &models.User{
 		ID:                 primitive.NewObjectID(),
 		FullName:           adminFullName,
@@ -45,6 +46,8 @@ func SeedAdmin() (*models.User, error) {
 		Role:               adminRole,
 		Status:             adminStatus,
 		TermsAndConditions: true,
+		Version:            1,
+		EmailVerifiedAt:    &now,
 		CreatedAt:          time.Now(),
 		UpdatedAt:          time.Now(),
 	}
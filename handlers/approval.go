@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"myfiberproject/config"
+	"myfiberproject/libs"
+	"myfiberproject/models"
+	"net/url"
+	"time"
+)
+
+// Ways the credentials of a newly approved user are delivered
+const (
+	credentialDeliveryEmail = "email" // Email the user a link to set their own password
+	credentialDeliveryAdmin = "admin" // Return a generated password once to the approving administrator
+)
+
+var credentialDeliveries = map[string]bool{
+	credentialDeliveryEmail: true,
+	credentialDeliveryAdmin: true,
+}
+
+func setPasswordTTL() time.Duration {
+	return time.Duration(config.GetEnvInt("SET_PASSWORD_TTL_HOURS", 72)) * time.Hour
+}
+
+// sendSetPasswordEmail invites an approved user to choose a password. The link uses the same
+// single-use tokens as password resets, with a longer lifetime.
+func sendSetPasswordEmail(ctx context.Context, user models.User) error {
+	ttl := setPasswordTTL()
+	token, err := createPasswordResetToken(ctx, user.ID, ttl)
+	if err != nil {
+		return err
+	}
+
+	link := frontendLink("/reset-password", url.Values{"token": {token}})
+	return sendMail(ctx, libs.Mail{
+		To:      user.Email,
+		Subject: "Your account has been approved",
+		Text: fmt.Sprintf("Hello %s,\n\nYour account has been approved. Use the link below to choose your password. It can be used once and expires in %d hours.\n\n%s\n",
+			user.FullName, int(ttl.Hours()), link),
+	})
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"myfiberproject/libs"
+	"myfiberproject/models"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// prepareArticleBody checks that an article has a body. When it has blocks, they are validated
+// and Content is derived from them as Markdown so that every consumer of Content keeps working.
+func prepareArticleBody(article *models.ArticleContent) error {
+	if len(article.Blocks) == 0 {
+		article.Blocks = nil
+		if strings.TrimSpace(article.Content) == "" {
+			return errors.New("content or blocks are required")
+		}
+		return nil
+	}
+
+	for i, block := range article.Blocks {
+		if err := validateArticleBlock(block); err != nil {
+			return fmt.Errorf("block %d (%s): %v", i, block.Type, err)
+		}
+	}
+
+	article.Content = renderBlocksMarkdown(article.Blocks)
+	article.ContentFormat = models.MarkdownFormat
+	return nil
+}
+
+// validateArticleBlock checks the fields required by the type of the block
+func validateArticleBlock(block models.ArticleBlock) error {
+	switch block.Type {
+	case models.ParagraphBlock, models.QuoteBlock, models.CodeBlock:
+		if strings.TrimSpace(block.Text) == "" {
+			return errors.New("text is required")
+		}
+	case models.HeadingBlock:
+		if strings.TrimSpace(block.Text) == "" {
+			return errors.New("text is required")
+		}
+		if block.Level < 1 || block.Level > 6 {
+			return errors.New("level must be between 1 and 6")
+		}
+	case models.ImageBlock:
+		if !isBlockURL(block.URL, true) {
+			return errors.New("url must be an http(s) URL or a path on this server")
+		}
+	case models.EmbedBlock:
+		if !isBlockURL(block.URL, false) {
+			return errors.New("url must be an https URL")
+		}
+	default:
+		return errors.New("unknown block type")
+	}
+	return nil
+}
+
+// isBlockURL accepts absolute http(s) URLs, and server-relative paths when allowRelative is set
+func isBlockURL(raw string, allowRelative bool) bool {
+	u, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		return false
+	}
+	if allowRelative && u.Scheme == "" && u.Host == "" && strings.HasPrefix(u.Path, "/") {
+		return true
+	}
+	return (u.Scheme == "https" || (allowRelative && u.Scheme == "http")) && u.Host != ""
+}
+
+// renderBlocksHTML renders blocks to sanitized HTML
+func renderBlocksHTML(blocks []models.ArticleBlock) string {
+	var b strings.Builder
+	for _, block := range blocks {
+		text := html.EscapeString(block.Text)
+		switch block.Type {
+		case models.ParagraphBlock:
+			fmt.Fprintf(&b, "<p>%s</p>\n", strings.ReplaceAll(text, "\n", "<br>"))
+		case models.HeadingBlock:
+			fmt.Fprintf(&b, "<h%d>%s</h%d>\n", block.Level, text, block.Level)
+		case models.ImageBlock:
+			fmt.Fprintf(&b, "<figure><img src=\"%s\" alt=\"%s\">", html.EscapeString(block.URL), html.EscapeString(block.Alt))
+			if block.Caption != "" {
+				fmt.Fprintf(&b, "<figcaption>%s</figcaption>", html.EscapeString(block.Caption))
+			}
+			b.WriteString("</figure>\n")
+		case models.QuoteBlock:
+			fmt.Fprintf(&b, "<blockquote><p>%s</p>", text)
+			if block.Citation != "" {
+				fmt.Fprintf(&b, "<cite>%s</cite>", html.EscapeString(block.Citation))
+			}
+			b.WriteString("</blockquote>\n")
+		case models.EmbedBlock:
+			// Embeds are rendered as links; the website decides how to display the embedded content
+			caption := block.Caption
+			if caption == "" {
+				caption = block.URL
+			}
+			fmt.Fprintf(&b, "<figure><a href=\"%s\">%s</a></figure>\n", html.EscapeString(block.URL), html.EscapeString(caption))
+		case models.CodeBlock:
+			if block.Language != "" {
+				fmt.Fprintf(&b, "<pre><code class=\"language-%s\">%s</code></pre>\n", html.EscapeString(block.Language), text)
+			} else {
+				fmt.Fprintf(&b, "<pre><code>%s</code></pre>\n", text)
+			}
+		}
+	}
+	return libs.SanitizeHTML(b.String())
+}
+
+// renderBlocksMarkdown renders blocks to Markdown. Text is escaped so that it comes out of the
+// Markdown renderer as typed, the way renderBlocksHTML shows it.
+func renderBlocksMarkdown(blocks []models.ArticleBlock) string {
+	parts := []string{}
+	for _, block := range blocks {
+		switch block.Type {
+		case models.ParagraphBlock:
+			parts = append(parts, markdownText(block.Text))
+		case models.HeadingBlock:
+			parts = append(parts, strings.Repeat("#", block.Level)+" "+markdownLine(block.Text))
+		case models.ImageBlock:
+			image := fmt.Sprintf("![%s](%s)", markdownLine(block.Alt), markdownURL(block.URL))
+			if block.Caption != "" {
+				image += "\n*" + markdownLine(block.Caption) + "*"
+			}
+			parts = append(parts, image)
+		case models.QuoteBlock:
+			quote := "> " + strings.ReplaceAll(markdownText(block.Text), "\n", "\n> ")
+			if block.Citation != "" {
+				quote += "\n>\n> — " + markdownLine(block.Citation)
+			}
+			parts = append(parts, quote)
+		case models.EmbedBlock:
+			caption := block.Caption
+			if caption == "" {
+				caption = block.URL
+			}
+			parts = append(parts, fmt.Sprintf("[%s](%s)", markdownLine(caption), markdownURL(block.URL)))
+		case models.CodeBlock:
+			fence := markdownFence(block.Text)
+			language := strings.Map(func(r rune) rune {
+				if r == '`' || unicode.IsSpace(r) {
+					return -1
+				}
+				return r
+			}, block.Language)
+			parts = append(parts, fence+language+"\n"+block.Text+"\n"+fence)
+		}
+	}
+	return strings.Join(parts, "\n\n") + "\n"
+}
+
+// markdownEscaper backslash-escapes the characters that start inline Markdown constructs: emphasis,
+// code spans, links, images, raw HTML, entities, tables and strikethrough, and also # and > so that
+// they can't start a heading or a quote
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`,
+	"&", `\&`, "|", `\|`, "~", `\~`, "#", `\#`,
+)
+
+// markdownListMarker matches what is left that can start a block at the beginning of a line:
+// list items, ordered list items and setext heading underlines
+var markdownListMarker = regexp.MustCompile(`^(\d{1,9})?([-+=.)])`)
+
+// markdownText escapes multi-line text. Leading whitespace is dropped from every line, as it
+// would otherwise turn the line into a code block.
+func markdownText(text string) string {
+	lines := strings.Split(markdownEscaper.Replace(text), "\n")
+	for i, line := range lines {
+		line = strings.TrimLeft(line, " \t")
+		if match := markdownListMarker.FindStringSubmatchIndex(line); match != nil {
+			marker := match[4]
+			// Digits alone or with a marker other than . and ) don't start anything
+			if match[2] < 0 || line[marker] == '.' || line[marker] == ')' {
+				line = line[:marker] + `\` + line[marker:]
+			}
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// markdownLine escapes text that has to stay on one line, such as headings, alt texts and captions
+func markdownLine(text string) string {
+	return markdownText(strings.Join(strings.Fields(text), " "))
+}
+
+// markdownURL encodes the characters that would end a link destination early
+var markdownURL = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E").Replace
+
+// markdownFence returns a code fence longer than the longest run of backticks in code
+func markdownFence(code string) string {
+	longest, run := 0, 0
+	for _, r := range code {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// blocksPlainText concatenates the text of all blocks, as used for AI enrichment
+func blocksPlainText(blocks []models.ArticleBlock) string {
+	parts := []string{}
+	for _, block := range blocks {
+		for _, text := range []string{block.Text, block.Alt, block.Caption, block.Citation} {
+			if strings.TrimSpace(text) != "" {
+				parts = append(parts, strings.TrimSpace(text))
+			}
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// articlePlainText returns the text of an article that AI enrichment works on
+func articlePlainText(article models.ArticleContent) string {
+	if len(article.Blocks) > 0 {
+		return blocksPlainText(article.Blocks)
+	}
+	return article.Content
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"myfiberproject/libs"
+	"myfiberproject/models"
+	"strings"
+	"testing"
+)
+
+func TestRenderBlocksMarkdown(t *testing.T) {
+	tests := []struct {
+		name  string
+		block models.ArticleBlock
+		want  string
+	}{
+		{
+			name:  "paragraph",
+			block: models.ArticleBlock{Type: models.ParagraphBlock, Text: "Plain words, nothing special."},
+			want:  "Plain words, nothing special.",
+		},
+		{
+			name:  "emphasis and code spans",
+			block: models.ArticleBlock{Type: models.ParagraphBlock, Text: "2*3*4 and snake_case_name with `ticks` and ~~gone~~"},
+			want:  "2\\*3\\*4 and snake\\_case\\_name with \\`ticks\\` and \\~\\~gone\\~\\~",
+		},
+		{
+			name:  "links, images and raw HTML",
+			block: models.ArticleBlock{Type: models.ParagraphBlock, Text: `![x](y) [a](javascript:alert(1)) <script>alert(2)</script> &amp; a\b`},
+			want:  `!\[x\](y) \[a\](javascript:alert(1)) \<script\>alert(2)\</script\> \&amp; a\\b`,
+		},
+		{
+			name:  "block markers at the start of lines",
+			block: models.ArticleBlock{Type: models.ParagraphBlock, Text: "# not a heading\n> not a quote\n- not a list\n+ nor this\n1. nor this\n2) nor this\n    not code\n===\n2024-01-01 is a date\n| a | b |"},
+			want:  "\\# not a heading\n\\> not a quote\n\\- not a list\n\\+ nor this\n1\\. nor this\n2\\) nor this\nnot code\n\\===\n2024-01-01 is a date\n\\| a \\| b \\|",
+		},
+		{
+			name:  "heading",
+			block: models.ArticleBlock{Type: models.HeadingBlock, Level: 2, Text: "C# *and*\n# more"},
+			want:  "## C\\# \\*and\\* \\# more",
+		},
+		{
+			name:  "quote",
+			block: models.ArticleBlock{Type: models.QuoteBlock, Text: "First *line*\n- second", Citation: "[Someone]"},
+			want:  "> First \\*line\\*\n> \\- second\n>\n> — \\[Someone\\]",
+		},
+		{
+			name:  "image",
+			block: models.ArticleBlock{Type: models.ImageBlock, URL: "/media/a (1).png", Alt: "a ] b", Caption: "Photo: *me*\nhere"},
+			want:  "![a \\] b](/media/a%20%281%29.png)\n*Photo: \\*me\\* here*",
+		},
+		{
+			name:  "embed",
+			block: models.ArticleBlock{Type: models.EmbedBlock, URL: "https://example.com/watch?v=1&t=2", Caption: "A [video]"},
+			want:  "[A \\[video\\]](https://example.com/watch?v=1&t=2)",
+		},
+		{
+			name:  "embed without caption",
+			block: models.ArticleBlock{Type: models.EmbedBlock, URL: "https://example.com/a_b"},
+			want:  "[https://example.com/a\\_b](https://example.com/a_b)",
+		},
+		{
+			name:  "code",
+			block: models.ArticleBlock{Type: models.CodeBlock, Language: "go", Text: "x := *p // [not] <escaped>"},
+			want:  "```go\nx := *p // [not] <escaped>\n```",
+		},
+		{
+			name:  "code containing a fence",
+			block: models.ArticleBlock{Type: models.CodeBlock, Language: "md", Text: "```\nnested\n```\n`````x"},
+			want:  "``````md\n```\nnested\n```\n`````x\n``````",
+		},
+		{
+			name:  "code language can't break the fence",
+			block: models.ArticleBlock{Type: models.CodeBlock, Language: "go``` evil", Text: "x"},
+			want:  "```goevil\nx\n```",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := renderBlocksMarkdown([]models.ArticleBlock{test.block})
+			if got != test.want+"\n" {
+				t.Errorf("renderBlocksMarkdown() =\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRenderBlocksMarkdownKeepsText(t *testing.T) {
+	// Once rendered, escaped text reads as typed and adds no elements of its own
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "2*3*4 = 24", want: "<p>2*3*4 = 24</p>"},
+		{text: "# Not a heading", want: "<p># Not a heading</p>"},
+		{text: "1. Not a list", want: "<p>1. Not a list</p>"},
+		{text: "- Not a list", want: "<p>- Not a list</p>"},
+		{text: "[a](/local) ![b](/c.png)", want: "<p>[a](/local) ![b](/c.png)</p>"},
+		{text: "<b>bold</b> &amp;", want: "<p>&lt;b&gt;bold&lt;/b&gt; &amp;amp;</p>"},
+		{text: "a_b_c `x` ~~y~~", want: "<p>a_b_c `x` ~~y~~</p>"},
+		{text: `back\slash \*`, want: `<p>back\slash \*</p>`},
+	}
+
+	for _, test := range tests {
+		markdown := renderBlocksMarkdown([]models.ArticleBlock{{Type: models.ParagraphBlock, Text: test.text}})
+		got, err := libs.RenderContent(string(models.MarkdownFormat), markdown)
+		if err != nil {
+			t.Fatalf("RenderContent: %v", err)
+		}
+		if got = strings.TrimSpace(got); got != test.want {
+			t.Errorf("%q renders as %q, want %q", test.text, got, test.want)
+		}
+	}
+}
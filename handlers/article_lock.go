@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"myfiberproject/config"
+	"myfiberproject/database"
+	"myfiberproject/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Edit locks complement the version checks of UpdateArticleContent: they warn editors that someone
+// else has the article open, but do not block updates. A lock expires ARTICLE_LOCK_TTL_SECONDS after
+// the last heartbeat, so a closed browser tab does not keep an article locked.
+
+func articleLockCollection() *mongo.Collection {
+	return database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_locks")
+}
+
+func articleLockTTL() time.Duration {
+	return time.Duration(config.GetEnvInt("ARTICLE_LOCK_TTL_SECONDS", 120)) * time.Second
+}
+
+// AcquireArticleLock locks an article for the current user, or renews the lock they already hold.
+// When another user holds the lock it responds with 423 and the current lock.
+func AcquireArticleLock(c *fiber.Ctx) error {
+	return lockArticle(c, false)
+}
+
+// RenewArticleLock is the heartbeat of an edit session. Unlike AcquireArticleLock, it fails with 409
+// when the current user no longer holds the lock, so the editor knows the lock lapsed.
+func RenewArticleLock(c *fiber.Ctx) error {
+	return lockArticle(c, true)
+}
+
+func lockArticle(c *fiber.Ctx, renewOnly bool) error {
+	articleID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+	userID, err := currentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	collection := articleLockCollection()
+
+	if renewOnly {
+		var lock models.ArticleLock
+		err := collection.FindOneAndUpdate(
+			ctx,
+			bson.M{"_id": articleID, "user_id": userID, "expires_at": bson.M{"$gt": now}},
+			bson.M{"$set": bson.M{"heartbeat_at": now, "expires_at": now.Add(articleLockTTL())}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&lock)
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "You do not hold the lock on this article, acquire it again"})
+		}
+		if err != nil {
+			log.Println("Failed to renew article lock:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to renew lock"})
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": lock})
+	}
+
+	contentCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_content")
+	if count, err := contentCollection.CountDocuments(ctx, bson.M{"_id": articleID}); err != nil || count == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Data not found"})
+	}
+
+	var user models.User
+	if err := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	// Take the lock when it is free, expired or already ours. If another user holds it the filter
+	// does not match and the upsert collides with their lock on _id.
+	var lock models.ArticleLock
+	err = collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": articleID, "$or": bson.A{bson.M{"user_id": userID}, bson.M{"expires_at": bson.M{"$lte": now}}}},
+		bson.A{bson.M{"$set": bson.M{
+			// Keep the acquisition time when the user renews their own lock
+			"acquired_at":  bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$user_id", userID}}, "$acquired_at", now}},
+			"user_id":      userID,
+			"user_name":    user.FullName,
+			"heartbeat_at": now,
+			"expires_at":   now.Add(articleLockTTL()),
+		}}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&lock)
+	if mongo.IsDuplicateKeyError(err) {
+		var holder models.ArticleLock
+		if err := collection.FindOne(ctx, bson.M{"_id": articleID}).Decode(&holder); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to read lock"})
+		}
+		return c.Status(fiber.StatusLocked).JSON(fiber.Map{
+			"error": "The article is being edited by " + holder.UserName,
+			"data":  holder,
+		})
+	}
+	if err != nil {
+		log.Println("Failed to acquire article lock:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to acquire lock"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": lock})
+}
+
+// GetArticleLock shows who holds the lock on an article
+func GetArticleLock(c *fiber.Ctx) error {
+	articleID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The TTL monitor only runs every minute, so expired locks are filtered out here as well
+	var lock models.ArticleLock
+	err = articleLockCollection().FindOne(ctx, bson.M{"_id": articleID, "expires_at": bson.M{"$gt": time.Now()}}).Decode(&lock)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "The article is not locked"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": lock})
+}
+
+// ReleaseArticleLock releases the current user's lock. Administrators can release a lock held by
+// someone else with ?force=true.
+func ReleaseArticleLock(c *fiber.Ctx) error {
+	articleID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+	claims, err := currentClaims(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+	}
+
+	filter := bson.M{"_id": articleID, "user_id": userID}
+	if c.QueryBool("force") {
+		if claims.Role != string(models.Administrator) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only administrators can force-release a lock"})
+		}
+		filter = bson.M{"_id": articleID}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := articleLockCollection().DeleteOne(ctx, filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to release lock"})
+	}
+	if result.DeletedCount == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "You do not hold a lock on this article"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Lock released"})
+}
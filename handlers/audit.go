@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"myfiberproject/database"
+	"myfiberproject/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func auditLogCollection() *mongo.Collection {
+	return database.GetMongoClient().Database(database.GetDatabaseName()).Collection("audit_logs")
+}
+
+// recordAudit adds an entry to the audit log for an action taken in the request. The user making
+// the request is recorded as the actor. Failures are logged, the action itself has already happened.
+func recordAudit(ctx context.Context, c *fiber.Ctx, action models.AuditAction, targetID primitive.ObjectID, details map[string]interface{}) {
+	entry := models.AuditLog{
+		ID:        primitive.NewObjectID(),
+		Action:    action,
+		Details:   details,
+		IP:        c.IP(),
+		CreatedAt: time.Now(),
+	}
+	if actorID, err := currentUserID(c); err == nil {
+		entry.ActorID = &actorID
+	}
+	if !targetID.IsZero() {
+		entry.TargetID = &targetID
+	}
+
+	if _, err := auditLogCollection().InsertOne(ctx, entry); err != nil {
+		log.Printf("Failed to record audit log entry %s: %v", action, err)
+	}
+}
+
+// GetAuditLogs lists audit log entries, newest first, optionally filtered by action, actor_id or target_id
+func GetAuditLogs(c *fiber.Ctx) error {
+	filter := bson.M{}
+	if action := c.Query("action"); action != "" {
+		filter["action"] = action
+	}
+	for _, key := range []string{"actor_id", "target_id"} {
+		if value := c.Query(key); value != "" {
+			id, err := primitive.ObjectIDFromHex(value)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid " + key})
+			}
+			filter[key] = id
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := auditLogCollection()
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+
+	page := parsePagination(c)
+	cursor, err := collection.Find(ctx, filter, page.findOptions().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+
+	entries := []models.AuditLog{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error decoding data"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(page.response(entries, total))
+}
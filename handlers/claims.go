@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"errors"
+	"myfiberproject/middleware"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var errNoClaims = errors.New("request is not authenticated")
+
+// currentClaims returns the token claims stored by middleware.RequireRole
+func currentClaims(c *fiber.Ctx) (*middleware.CustomClaims, error) {
+	claims, ok := c.Locals("claims").(*middleware.CustomClaims)
+	if !ok || claims == nil {
+		return nil, errNoClaims
+	}
+	return claims, nil
+}
+
+// currentUserID returns the ID of the user making the request
+func currentUserID(c *fiber.Ctx) (primitive.ObjectID, error) {
+	claims, err := currentClaims(c)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return primitive.ObjectIDFromHex(claims.UserID)
+}
@@ -2,14 +2,24 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"log"
 	"myfiberproject/database"
+	"myfiberproject/models"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+var (
+	errSourceCategoryNotFound = errors.New("source article category not found")
+	errTargetCategoryNotFound = errors.New("target article category not found")
+)
+
+// DeleteArticleCategory deletes a category after moving its articles to a replacement category
 func DeleteArticleCategory(c *fiber.Ctx) error {
 	// Parse the ID from the URL parameter
 	id, err := primitive.ObjectIDFromHex(c.Params("id"))
@@ -17,17 +27,99 @@ func DeleteArticleCategory(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID"})
 	}
 
-	// Dynamically fetch the database name
-	db := database.GetMongoClient().Database(database.GetDatabaseName())
+	// A replacement is required so that no article is left pointing at a deleted category
+	replacementID, err := primitive.ObjectIDFromHex(c.Query("replacement_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A valid replacement_id query parameter is required"})
+	}
+	if replacementID == id {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Replacement category must differ from the deleted category"})
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Delete the document with the matching ID
-	_, err = db.Collection("activity_category").DeleteOne(ctx, bson.M{"_id": id})
+	var articlesUpdated int64
+	err = database.WithTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		articlesUpdated, err = replaceArticleCategory(sessCtx, id, replacementID)
+		return err
+	})
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete data"})
+		return articleCategoryReplaceError(c, err)
 	}
 
+	removeSitemapEntry(ctx, id)
+
 	// Successfully deleted data
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Data successfully deleted"})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":          "Data successfully deleted",
+		"replacement_id":   replacementID.Hex(),
+		"articles_updated": articlesUpdated,
+	})
+}
+
+// replaceArticleCategory moves every article from the source category to the target
+// category and deletes the source. The source slugs are added to the target's slug history
+// so that links to the removed category keep working. It must run inside a transaction.
+func replaceArticleCategory(ctx context.Context, sourceID, targetID primitive.ObjectID) (int64, error) {
+	db := database.GetMongoClient().Database(database.GetDatabaseName())
+	categoryCollection := db.Collection("article_category")
+	contentCollection := db.Collection("article_content")
+
+	if err := categoryCollection.FindOne(ctx, bson.M{"_id": targetID}).Err(); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, errTargetCategoryNotFound
+		}
+		return 0, err
+	}
+
+	var source models.ArticleCategory
+	if err := categoryCollection.FindOneAndDelete(ctx, bson.M{"_id": sourceID}).Decode(&source); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, errSourceCategoryNotFound
+		}
+		return 0, err
+	}
+
+	redirectSlugs := source.PreviousSlugs
+	if source.Slug != "" {
+		redirectSlugs = append(redirectSlugs, source.Slug)
+	}
+	if len(redirectSlugs) > 0 {
+		_, err := categoryCollection.UpdateOne(ctx, bson.M{"_id": targetID}, bson.M{
+			"$addToSet": bson.M{"previous_slugs": bson.M{"$each": redirectSlugs}},
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// $addToSet and $pull cannot target the same field in one update, so add the target first
+	filter := bson.M{"article_categories": sourceID}
+	result, err := contentCollection.UpdateMany(ctx, filter, bson.M{
+		"$addToSet": bson.M{"article_categories": targetID},
+		"$set":      bson.M{"updated_at": time.Now()},
+		"$inc":      incrementVersion,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if _, err := contentCollection.UpdateMany(ctx, filter, bson.M{"$pull": bson.M{"article_categories": sourceID}}); err != nil {
+		return 0, err
+	}
+
+	return result.MatchedCount, nil
+}
+
+// articleCategoryReplaceError maps errors from replaceArticleCategory to HTTP responses
+func articleCategoryReplaceError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errSourceCategoryNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Data not found"})
+	case errors.Is(err, errTargetCategoryNotFound):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Replacement category not found"})
+	default:
+		log.Println("Failed to reassign article category:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reassign article category"})
+	}
 }
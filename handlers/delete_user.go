@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"log"
 	"myfiberproject/database"
 	"time"
 
@@ -28,6 +29,11 @@ func DeleteUser(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete user"})
 	}
 
+	// Log the deleted user out of every session
+	if err := endAllSessions(ctx, id); err != nil {
+		log.Println("Failed to end sessions of deleted user:", err)
+	}
+
 	// Successfully deleted the user
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "User successfully deleted"})
 }
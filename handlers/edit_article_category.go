@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"myfiberproject/database"
+	"myfiberproject/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UpdateArticleCategory updates the name and slug of an existing article category.
+// When the slug changes, the old slug is kept so that it redirects to the new one.
+func UpdateArticleCategory(c *fiber.Ctx) error {
+	// Parse the ID from the URL parameter
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	var articleCategory models.ArticleCategory
+	if err := c.BodyParser(&articleCategory); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse request"})
+	}
+
+	// Validate the struct
+	if validationErr := validate.Struct(&articleCategory); validationErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": validationErr.Error()})
+	}
+
+	// Dynamically fetch the database name
+	articleCategoryCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_category")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var existing models.ArticleCategory
+	if err := articleCategoryCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&existing); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Data not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+
+	// Regenerate the slug when an explicit slug is given or the name changes
+	slug := existing.Slug
+	if articleCategory.Slug != "" && articleCategory.Slug != existing.Slug {
+		slug, err = uniqueSlug(ctx, articleCategoryCollection, articleCategory.Slug, id)
+	} else if articleCategory.Slug == "" && (articleCategory.Name != existing.Name || existing.Slug == "") {
+		slug, err = uniqueSlug(ctx, articleCategoryCollection, articleCategory.Name, id)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate slug"})
+	}
+
+	var updated models.ArticleCategory
+	err = articleCategoryCollection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{
+			"name":           articleCategory.Name,
+			"slug":           slug,
+			"previous_slugs": nextPreviousSlugs(existing.PreviousSlugs, existing.Slug, slug),
+			"updated_at":     time.Now(),
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Data not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update data"})
+	}
+
+	syncCategorySitemapEntry(ctx, updated)
+
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
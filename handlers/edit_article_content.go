@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"myfiberproject/database"
+	"myfiberproject/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UpdateArticleContent replaces the editable fields of an article.
+// AI-generated fields are kept, and the image is only replaced when a new one is given.
+// The If-Match header must carry the ETag the article was read with.
+func UpdateArticleContent(c *fiber.Ctx) error {
+	// Parse the ID from the URL parameter
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	var articleContent models.ArticleContent
+	if err := c.BodyParser(&articleContent); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse request"})
+	}
+
+	// Validate the struct
+	if validationErr := validate.Struct(&articleContent); validationErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": validationErr.Error()})
+	}
+	if err := prepareArticleBody(&articleContent); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	contentCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_content")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var existing models.ArticleContent
+	if err := contentCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&existing); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Data not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+	if ok, err := checkIfMatch(c, existing.Version); !ok {
+		return err
+	}
+
+	// Regenerate the slug when an explicit slug is given or the title changes
+	slug := existing.Slug
+	if articleContent.Slug != "" && articleContent.Slug != existing.Slug {
+		slug, err = uniqueSlug(ctx, contentCollection, articleContent.Slug, id)
+	} else if articleContent.Slug == "" && (articleContent.Title != existing.Title || existing.Slug == "") {
+		slug, err = uniqueSlug(ctx, contentCollection, articleContent.Title, id)
+	}
+	if err != nil {
+		log.Println("Failed to generate article slug:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate slug"})
+	}
+
+	// Attach the free-form tags, creating the ones that do not exist yet
+	tags, err := resolveTags(ctx, articleContent.Tags)
+	if err != nil {
+		log.Println("Failed to resolve article tags:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save tags"})
+	}
+
+	update := bson.M{
+		"title":              articleContent.Title,
+		"slug":               slug,
+		"previous_slugs":     nextPreviousSlugs(existing.PreviousSlugs, existing.Slug, slug),
+		"excerpt":            articleContent.Excerpt,
+		"content":            articleContent.Content,
+		"blocks":             articleContent.Blocks,
+		"article_categories": articleContent.ArticleCategories,
+		"tags":               tags,
+		"updated_at":         time.Now(),
+	}
+	if articleContent.Image != "" {
+		update["image"] = articleContent.Image
+	}
+	if articleContent.ContentFormat != "" {
+		update["content_format"] = articleContent.ContentFormat
+	}
+
+	// Keep the status unless a new one is given, and remember when the article was first published
+	if articleContent.Status != "" {
+		update["status"] = articleContent.Status
+		if articleContent.Status == models.Published && existing.PublishedAt == nil {
+			update["published_at"] = time.Now()
+		}
+	}
+
+	var updated models.ArticleContent
+	err = contentCollection.FindOneAndUpdate(
+		ctx,
+		versionFilter(id, existing.Version),
+		bson.M{"$set": update, "$inc": incrementVersion},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		// The article was found above, so it was changed by another write in the meantime
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{"error": errVersionMismatch.Error()})
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Slug is already in use"})
+		}
+		log.Println("Failed to update article content:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update article content"})
+	}
+
+	if err := refreshTagUsage(ctx, existing.Tags, updated.Tags); err != nil {
+		log.Println("Failed to refresh tag usage:", err)
+	}
+	syncArticleSitemapEntry(ctx, updated)
+
+	renderArticleContent(&updated)
+
+	setVersionETag(c, updated.Version)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Article content updated successfully",
+		"data":    updated,
+	})
+}
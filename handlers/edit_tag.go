@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"myfiberproject/database"
+	"myfiberproject/libs"
+	"myfiberproject/models"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RenameTag renames a tag and every article that uses it
+func RenameTag(c *fiber.Ctx) error {
+	// Parse the ID from the URL parameter
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	var tag models.Tag
+	if err := c.BodyParser(&tag); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse request"})
+	}
+	tag.Name = strings.Join(strings.Fields(tag.Name), " ")
+	if validationErr := validate.Struct(&tag); validationErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": validationErr.Error()})
+	}
+
+	slug := libs.Slugify(tag.Name)
+	if slug == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tag name must contain letters or digits"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var existing models.Tag
+	if err := tagCollection().FindOne(ctx, bson.M{"_id": id}).Decode(&existing); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Tag not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+
+	// Another tag with the same slug must be merged instead of renamed into
+	count, err := tagCollection().CountDocuments(ctx, bson.M{"slug": slug, "_id": bson.M{"$ne": id}})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+	if count > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A tag with this name already exists, merge the tags instead"})
+	}
+
+	contentCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_content")
+	var updated models.Tag
+	err = database.WithTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		_, err := contentCollection.UpdateMany(
+			sessCtx,
+			bson.M{"tags": existing.Name},
+			bson.M{"$set": bson.M{"tags.$[tag]": tag.Name, "updated_at": time.Now()}, "$inc": incrementVersion},
+			options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"tag": existing.Name}}}),
+		)
+		if err != nil {
+			return err
+		}
+
+		return tagCollection().FindOneAndUpdate(
+			sessCtx,
+			bson.M{"_id": id},
+			bson.M{"$set": bson.M{"name": tag.Name, "slug": slug, "updated_at": time.Now()}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&updated)
+	})
+	if err != nil {
+		log.Println("Failed to rename tag:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to rename tag"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
@@ -9,11 +9,13 @@ import (
 	"myfiberproject/models"
 	"net/http"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -35,15 +37,25 @@ func hashPassword(password string) (string, error) {
 	return string(hashedPassword), nil
 }
 
+// readOnlyUserFields cannot be changed through prepareUpdateData
+var readOnlyUserFields = map[string]bool{
+	"version":           true,
+	"email_verified_at": true,
+	"mfa_enabled_at":    true,
+	"oidc_issuer":       true,
+	"created_at":        true,
+	"updated_at":        true,
+}
+
 func prepareUpdateData(userUpdate models.User) (bson.M, error) {
-	updateData := bson.M{"$set": bson.M{}}
+	updateData := bson.M{"$set": bson.M{}, "$inc": incrementVersion}
 	val := reflect.ValueOf(userUpdate)
 	for i := 0; i < val.Type().NumField(); i++ {
 		field := val.Type().Field(i)
 		value := val.Field(i)
-		jsonTag := field.Tag.Get("json")
+		jsonTag := strings.Split(field.Tag.Get("json"), ",")[0] // Drop options such as omitempty
 
-		if jsonTag != "" && jsonTag != "-" && !value.IsZero() {
+		if jsonTag != "" && jsonTag != "-" && !readOnlyUserFields[jsonTag] && !value.IsZero() {
 			if jsonTag == "password" {
 				hashedPassword, err := hashPassword(value.String())
 				if err != nil {
@@ -77,20 +89,49 @@ func UpdateUser(c *fiber.Ctx) error {
 	var existingUser models.User
 	err = userCollection.FindOne(ctx, bson.M{"_id": userID}).Decode(&existingUser)
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		}
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching user from database"})
 	}
 
+	// Reject updates based on a stale copy of the user
+	if ok, err := checkIfMatch(c, existingUser.Version); !ok {
+		return err
+	}
+
+	approving := existingUser.Status == "pending" && userUpdate.Status == "approved"
+	delivery := c.Query("credential_delivery", credentialDeliveryEmail)
+	if approving && !credentialDeliveries[delivery] {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "credential_delivery must be email or admin"})
+	}
+
 	updateData, err := prepareUpdateData(userUpdate)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error processing update data"})
 	}
 
-	if existingUser.Status == "pending" && userUpdate.Status == "approved" {
-		defaultPassword, err := generatePassword()
+	// Changing the address requires verifying the new one
+	if userUpdate.Email != "" && userUpdate.Email != existingUser.Email {
+		updateData["$unset"] = bson.M{"email_verified_at": ""}
+	}
+
+	// The password chosen at signup is replaced, the user receives new credentials on approval
+	var generatedPassword string
+	if approving {
+		// Only verified addresses are approved, unless the administrator explicitly overrides it
+		if existingUser.EmailVerifiedAt == nil && !c.QueryBool("skip_email_verification") {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "The user has not verified their email address yet, pass skip_email_verification=true to approve anyway"})
+		}
+		if existingUser.EmailVerifiedAt == nil {
+			log.Printf("User %s approved without a verified email address", existingUser.Email)
+		}
+
+		generatedPassword, err = generatePassword()
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate password"})
 		}
-		hashedPassword, err := hashPassword(defaultPassword)
+		hashedPassword, err := hashPassword(generatedPassword)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to hash password"})
 		}
@@ -98,15 +139,50 @@ func UpdateUser(c *fiber.Ctx) error {
 		updateData["$set"].(bson.M)["password"] = hashedPassword
 	}
 
-	result, err := userCollection.UpdateOne(ctx, bson.M{"_id": userID}, updateData)
+	result, err := userCollection.UpdateOne(ctx, versionFilter(userID, existingUser.Version), updateData)
 	if err != nil {
 		log.Printf("Error updating user in database: %v", err)
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating user in database"})
 	}
 
+	// The user was found above, so it was changed by another write in the meantime
 	if result.MatchedCount == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{"error": errVersionMismatch.Error()})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "User updated successfully"})
+	setVersionETag(c, existingUser.Version+1)
+	if !approving {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "User updated successfully"})
+	}
+
+	recordAudit(ctx, c, models.AuditUserApproved, userID, map[string]interface{}{
+		"email":                      existingUser.Email,
+		"credential_delivery":        delivery,
+		"email_verification_skipped": existingUser.EmailVerifiedAt == nil,
+	})
+
+	if delivery == credentialDeliveryAdmin {
+		// The password is only ever shown in this response, make sure it is not kept anywhere
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message":  "User approved, hand the password to the user and ask them to change it",
+			"password": generatedPassword,
+		})
+	}
+
+	approvedUser := existingUser
+	if userUpdate.Email != "" {
+		approvedUser.Email = userUpdate.Email
+	}
+	if userUpdate.FullName != "" {
+		approvedUser.FullName = userUpdate.FullName
+	}
+	if err := sendSetPasswordEmail(ctx, approvedUser); err != nil {
+		log.Printf("Failed to send set password email to %s: %v", approvedUser.Email, err)
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "User approved, but the set password email could not be sent",
+			"warning": "Ask the user to request a password reset to set their password",
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "User approved, a link to set their password was emailed to them"})
 }
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"myfiberproject/config"
+	"myfiberproject/database"
+	"myfiberproject/models"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	enrichmentQueue     chan primitive.ObjectID
+	enrichmentQueueOnce sync.Once
+)
+
+// enrichmentWorkers starts the AI_ENRICHMENT_CONCURRENCY workers that send queued articles to OpenAI,
+// and returns their queue, which holds up to AI_ENRICHMENT_QUEUE_SIZE articles
+func enrichmentWorkers() chan<- primitive.ObjectID {
+	enrichmentQueueOnce.Do(func() {
+		enrichmentQueue = make(chan primitive.ObjectID, config.GetEnvInt("AI_ENRICHMENT_QUEUE_SIZE", 1000))
+		for i := 0; i < config.GetEnvInt("AI_ENRICHMENT_CONCURRENCY", 4); i++ {
+			go enrichmentWorker(enrichmentQueue)
+		}
+	})
+	return enrichmentQueue
+}
+
+func enrichmentWorker(queue <-chan primitive.ObjectID) {
+	for id := range queue {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		if err := enrichStoredArticleContent(ctx, id); err != nil {
+			log.Printf("Failed to enrich article %s: %v", id.Hex(), err)
+		}
+		cancel()
+	}
+}
+
+// queueArticleEnrichment enriches stored articles in the background. The articles were stored with a
+// pending enrichment status, so when the queue is full they are left for RequeueArticleEnrichment.
+func queueArticleEnrichment(articles []models.ArticleContent) {
+	queue := enrichmentWorkers()
+	for _, articleContent := range articles {
+		select {
+		case queue <- articleContent.ID:
+		default:
+			log.Printf("Enrichment queue is full, article %s is left pending until the next start", articleContent.ID.Hex())
+		}
+	}
+}
+
+// RequeueArticleEnrichment queues the articles whose enrichment is still pending, such as the ones
+// queued before a restart. It waits for room in the queue, so it is meant to run in the background
+// on startup.
+func RequeueArticleEnrichment() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	contentCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_content")
+	cursor, err := contentCollection.Find(ctx, bson.M{"enrichment_status": models.EnrichmentPending},
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		log.Println("Failed to find articles pending enrichment:", err)
+		return
+	}
+	var pending []models.ArticleContent
+	if err := cursor.All(ctx, &pending); err != nil {
+		log.Println("Failed to find articles pending enrichment:", err)
+		return
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	log.Printf("Requeuing %d articles pending enrichment", len(pending))
+	queue := enrichmentWorkers()
+	for _, articleContent := range pending {
+		queue <- articleContent.ID
+	}
+}
+
+// enrichStoredArticleContent runs AI enrichment for an article that is already in the database and still
+// pending. The recommendations and the image are derived by the server, so they are written in one update
+// that does not change the version editors hold. Recommendations are saved even when the image cannot be
+// generated, in which case the enrichment is marked as failed.
+func enrichStoredArticleContent(ctx context.Context, id primitive.ObjectID) error {
+	contentCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_content")
+
+	// Articles deleted or enriched since they were queued are skipped
+	var articleContent models.ArticleContent
+	err := contentCollection.FindOne(ctx, bson.M{"_id": id, "enrichment_status": models.EnrichmentPending}).Decode(&articleContent)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := recommendArticleContent(ctx, &articleContent); err != nil {
+		if _, updateErr := contentCollection.UpdateOne(ctx, bson.M{"_id": id},
+			bson.M{"$set": bson.M{"enrichment_status": models.EnrichmentFailed}}); updateErr != nil {
+			log.Printf("Failed to mark the enrichment of article %s as failed: %v", id.Hex(), updateErr)
+		}
+		return err
+	}
+	imageErr := generateArticleImage(&articleContent)
+
+	// Keep an image that was set while the enrichment was running
+	set := bson.M{
+		"recommended_categories": bson.M{"$literal": articleContent.RecommendedCategories},
+		"recommended_tags":       bson.M{"$literal": articleContent.RecommendedTags},
+		"enrichment_status":      models.EnrichmentDone,
+	}
+	if imageErr == nil {
+		set["image"] = bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$image", ""}}, ""}},
+			bson.M{"$literal": articleContent.Image},
+			"$image",
+		}}
+	} else {
+		set["enrichment_status"] = models.EnrichmentFailed
+	}
+
+	if _, err := contentCollection.UpdateOne(ctx, bson.M{"_id": id}, bson.A{bson.M{"$set": set}}); err != nil {
+		return err
+	}
+	return imageErr
+}
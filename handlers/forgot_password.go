@@ -2,74 +2,104 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"log"
-	"math/rand"
+	"myfiberproject/config"
 	"myfiberproject/database"
+	"myfiberproject/libs"
 	"myfiberproject/models"
+	"net/url"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"golang.org/x/crypto/bcrypt"
 )
 
-// GenerateRandomPassword generates a random password of the given length
-func GenerateRandomPassword(n int) string {
-	var letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
-	s := make([]rune, n)
-	for i := range s {
-		s[i] = letters[rand.Intn(len(letters))]
-	}
-	return string(s)
+// forgotPasswordMessage is returned whether or not the address belongs to a user, so that the
+// endpoint cannot be used to find out who has an account
+const forgotPasswordMessage = "If an approved account uses this email address, a link to reset the password has been sent to it."
+
+func passwordResetCollection() *mongo.Collection {
+	return database.GetMongoClient().Database(database.GetDatabaseName()).Collection("password_resets")
+}
+
+func passwordResetTTL() time.Duration {
+	return time.Duration(config.GetEnvInt("PASSWORD_RESET_TTL_MINUTES", 60)) * time.Minute
 }
 
-// ForgotPassword handles the forgot password process
+// ForgotPassword emails a link with a single-use reset token to the user. The password is only
+// changed when the token is used at POST /reset-password.
 func ForgotPassword(c *fiber.Ctx) error {
 	// Fetch database name dynamically
 	userCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("users")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 	defer cancel()
 
 	type request struct {
-		Email string `json:"email"`
+		Email string `json:"email" validate:"required,email"`
 	}
 
 	var req request
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
 	}
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": validationErr.Error()})
+	}
 
 	var user models.User
 	err := userCollection.FindOne(ctx, bson.M{"email": req.Email, "status": "approved"}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": forgotPasswordMessage})
+	}
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Email not found or not approved"})
-		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
 	}
 
-	// Generate a new password
-	newPassword := GenerateRandomPassword(10)
+	token, err := createPasswordResetToken(ctx, user.ID, passwordResetTTL())
+	if err != nil {
+		log.Println("Failed to create password reset token:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create reset token"})
+	}
 
-	// Hash the new password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	link := frontendLink("/reset-password", url.Values{"token": {token}})
+	err = sendMail(ctx, libs.Mail{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Text: fmt.Sprintf("Hello %s,\n\nUse the link below to choose a new password. It can be used once and expires in %d minutes.\n\n%s\n\nIf you did not ask to reset your password, you can ignore this email.\n",
+			user.FullName, int(passwordResetTTL().Minutes()), link),
+	})
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to hash password"})
+		log.Printf("Failed to send password reset email to %s: %v", user.Email, err)
 	}
 
-	// Update user's password in the database
-	_, err = userCollection.UpdateOne(
-		ctx,
-		bson.M{"_id": user.ID},
-		bson.M{"$set": bson.M{"password": string(hashedPassword)}},
-	)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": forgotPasswordMessage})
+}
+
+// createPasswordResetToken replaces any outstanding reset token of the user with a new one
+func createPasswordResetToken(ctx context.Context, userID primitive.ObjectID, ttl time.Duration) (string, error) {
+	token, err := randomToken()
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update password"})
+		return "", err
 	}
 
-	// Return success message
-	successMessage := "Password reset successful. Please contact the administrator to retrieve your new password."
-	log.Println(successMessage)
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": successMessage})
+	collection := passwordResetCollection()
+	if _, err := collection.DeleteMany(ctx, bson.M{"user_id": userID}); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	_, err = collection.InsertOne(ctx, models.PasswordReset{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
 }
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"context"
+	"myfiberproject/database"
+	"myfiberproject/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetAllArticleContent fetches all articles, newest first
+func GetAllArticleContent(c *fiber.Ctx) error {
+	// Dynamically fetch the database name
+	contentCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_content")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := contentCollection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+
+	articles := []models.ArticleContent{}
+	if err := cursor.All(ctx, &articles); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error decoding data"})
+	}
+	renderArticleContents(articles)
+
+	return c.Status(fiber.StatusOK).JSON(articles)
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"context"
+	"myfiberproject/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetAllTags fetches all tags, most used first
+func GetAllTags(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "usage_count", Value: -1}, {Key: "name", Value: 1}})
+	cursor, err := tagCollection().Find(ctx, bson.M{}, findOptions)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+
+	tags := []models.Tag{}
+	if err := cursor.All(ctx, &tags); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error decoding data"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(tags)
+}
@@ -0,0 +1,397 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"myfiberproject/config"
+	"myfiberproject/database"
+	"myfiberproject/libs"
+	"myfiberproject/models"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type articleExport struct {
+	filter  bson.M
+	baseURL string // Base URL of this API, used to make media URLs absolute
+}
+
+type exportFormat struct {
+	contentType string
+	extension   string
+	write       func(ctx context.Context, w io.Writer, export articleExport) error
+}
+
+var exportFormats = map[string]exportFormat{
+	"ndjson":   {contentType: "application/x-ndjson", extension: "ndjson", write: writeNDJSONExport},
+	"markdown": {contentType: "application/zip", extension: "zip", write: writeMarkdownExport},
+	"wxr":      {contentType: "application/xml; charset=utf-8", extension: "xml", write: writeWXRExport},
+}
+
+// ExportArticleContent streams all articles, optionally filtered by the "status" query parameter, as
+// newline-delimited JSON, a zip of Markdown files with front matter and images, or a WordPress WXR file.
+// Articles are read from a cursor and written as they come, so large exports are never held in memory.
+func ExportArticleContent(c *fiber.Ctx) error {
+	formatName := c.Query("format", "ndjson")
+	format, ok := exportFormats[formatName]
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format must be one of ndjson, markdown or wxr"})
+	}
+
+	export := articleExport{filter: bson.M{}, baseURL: c.BaseURL()}
+	if status := c.Query("status"); status != "" {
+		export.filter["status"] = status
+	}
+
+	c.Set(fiber.HeaderContentType, format.contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="articles-%s.%s"`, time.Now().Format("20060102"), format.extension))
+
+	// The stream writer runs after the handler has returned, so it needs a context of its own
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		defer cancel()
+
+		if err := format.write(ctx, w, export); err != nil {
+			log.Printf("Failed to export articles as %s: %v", formatName, err)
+		}
+		if err := w.Flush(); err != nil {
+			log.Println("Failed to flush article export:", err)
+		}
+	})
+	return nil
+}
+
+// forEachExportArticle calls fn for every article matching the export filter, oldest first
+func forEachExportArticle(ctx context.Context, export articleExport, fn func(article models.ArticleContent) error) error {
+	contentCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_content")
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetBatchSize(100)
+	cursor, err := contentCollection.Find(ctx, export.filter, findOptions)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var article models.ArticleContent
+		if err := cursor.Decode(&article); err != nil {
+			return err
+		}
+		renderArticleContent(&article)
+		if err := fn(article); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+func loadExportCategories(ctx context.Context) ([]models.ArticleCategory, map[primitive.ObjectID]models.ArticleCategory, error) {
+	categoryCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_category")
+	cursor, err := categoryCollection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, nil, err
+	}
+	var categories []models.ArticleCategory
+	if err := cursor.All(ctx, &categories); err != nil {
+		return nil, nil, err
+	}
+
+	byID := map[primitive.ObjectID]models.ArticleCategory{}
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+	return categories, byID, nil
+}
+
+func loadExportTags(ctx context.Context) ([]models.Tag, error) {
+	cursor, err := tagCollection().Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var tags []models.Tag
+	if err := cursor.All(ctx, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// writeNDJSONExport writes one JSON object per line: categories and tags first, then articles
+func writeNDJSONExport(ctx context.Context, w io.Writer, export articleExport) error {
+	encoder := json.NewEncoder(w)
+	writeRecord := func(recordType string, data interface{}) error {
+		return encoder.Encode(fiber.Map{"type": recordType, "data": data})
+	}
+
+	categories, _, err := loadExportCategories(ctx)
+	if err != nil {
+		return err
+	}
+	for _, category := range categories {
+		if err := writeRecord("category", category); err != nil {
+			return err
+		}
+	}
+
+	tags, err := loadExportTags(ctx)
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if err := writeRecord("tag", tag); err != nil {
+			return err
+		}
+	}
+
+	return forEachExportArticle(ctx, export, func(article models.ArticleContent) error {
+		return writeRecord("article", article)
+	})
+}
+
+// writeMarkdownExport writes a zip with one Markdown file per article under articles/ and the
+// article images under images/. Image references are rewritten to the bundled copies.
+func writeMarkdownExport(ctx context.Context, w io.Writer, export articleExport) error {
+	_, categoriesByID, err := loadExportCategories(ctx)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	bundledImages := map[string]string{} // Image URL to path in the archive
+
+	bundleImage := func(imageURL string) (string, bool) {
+		if bundled, ok := bundledImages[imageURL]; ok {
+			return bundled, bundled != ""
+		}
+		bundledImages[imageURL] = ""
+
+		data, err := loadExportImage(imageURL)
+		if err != nil {
+			log.Printf("Failed to bundle image %s: %v", imageURL, err)
+			return "", false
+		}
+		name := fmt.Sprintf("images/%d-%s", len(bundledImages), path.Base(strings.SplitN(imageURL, "?", 2)[0]))
+		file, err := archive.Create(name)
+		if err != nil {
+			return "", false
+		}
+		if _, err := file.Write(data); err != nil {
+			return "", false
+		}
+		bundledImages[imageURL] = name
+		return name, true
+	}
+
+	err = forEachExportArticle(ctx, export, func(article models.ArticleContent) error {
+		content := article.Content
+		image := article.Image
+		if image != "" {
+			if bundled, ok := bundleImage(image); ok {
+				image = "../" + bundled
+			}
+		}
+		for _, ref := range localMediaPattern().FindAllString(content, -1) {
+			if bundled, ok := bundleImage(ref); ok {
+				content = replaceImageRef(content, ref, "../"+bundled)
+			}
+		}
+
+		categoryNames := []string{}
+		for _, id := range article.ArticleCategories {
+			if category, ok := categoriesByID[id]; ok {
+				categoryNames = append(categoryNames, category.Name)
+			}
+		}
+
+		var b strings.Builder
+		b.WriteString("---\n")
+		writeFrontMatter(&b, "title", strconv.Quote(article.Title))
+		writeFrontMatter(&b, "slug", article.Slug)
+		writeFrontMatter(&b, "excerpt", strconv.Quote(article.Excerpt))
+		writeFrontMatter(&b, "status", string(article.Status))
+		writeFrontMatter(&b, "content_format", string(article.ContentFormat))
+		writeFrontMatter(&b, "image", strconv.Quote(image))
+		writeFrontMatter(&b, "categories", frontMatterList(categoryNames))
+		writeFrontMatter(&b, "tags", frontMatterList(article.Tags))
+		writeFrontMatter(&b, "created_at", article.CreatedAt.UTC().Format(time.RFC3339))
+		writeFrontMatter(&b, "updated_at", article.UpdatedAt.UTC().Format(time.RFC3339))
+		if article.PublishedAt != nil {
+			writeFrontMatter(&b, "published_at", article.PublishedAt.UTC().Format(time.RFC3339))
+		}
+		b.WriteString("---\n\n")
+		b.WriteString(content)
+
+		name := article.Slug
+		if name == "" {
+			name = article.ID.Hex()
+		}
+		file, err := archive.Create("articles/" + name + ".md")
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(file, b.String())
+		return err
+	})
+	if err != nil {
+		archive.Close()
+		return err
+	}
+	return archive.Close()
+}
+
+func writeFrontMatter(b *strings.Builder, key, value string) {
+	b.WriteString(key + ": " + value + "\n")
+}
+
+func frontMatterList(items []string) string {
+	quoted := make([]string, 0, len(items))
+	for _, item := range items {
+		quoted = append(quoted, strconv.Quote(item))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// localMediaPattern matches URLs of files in media storage
+func localMediaPattern() *regexp.Regexp {
+	return regexp.MustCompile(regexp.QuoteMeta(mediaBaseURL()+"/") + `[0-9a-f]{24}\.[a-z]+`)
+}
+
+// loadExportImage reads an image from media storage, or downloads it when it is hosted elsewhere
+func loadExportImage(imageURL string) ([]byte, error) {
+	if strings.HasPrefix(imageURL, mediaBaseURL()+"/") {
+		return os.ReadFile(filepath.Join(MediaDir(), filepath.Base(imageURL)))
+	}
+	return downloadMedia(imageURL)
+}
+
+// writeWXRExport writes a WordPress WXR file with categories, tags, posts and their featured images
+func writeWXRExport(ctx context.Context, w io.Writer, export articleExport) error {
+	siteURL := publicSiteURL()
+	wxr, err := libs.NewWXRWriter(w, libs.WXRSite{
+		Title:       config.GetEnv("PUBLIC_SITE_NAME", "Marno"),
+		Link:        siteURL,
+		Language:    "en",
+		BaseSiteURL: siteURL,
+		BaseBlogURL: siteURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	categories, categoriesByID, err := loadExportCategories(ctx)
+	if err != nil {
+		return err
+	}
+	for i, category := range categories {
+		err := wxr.WriteCategory(libs.WXRCategory{TermID: i + 1, Nicename: category.Slug, Name: libs.CDATA{Value: category.Name}})
+		if err != nil {
+			return err
+		}
+	}
+
+	tags, err := loadExportTags(ctx)
+	if err != nil {
+		return err
+	}
+	for i, tag := range tags {
+		err := wxr.WriteTag(libs.WXRTag{TermID: len(categories) + i + 1, Slug: tag.Slug, Name: libs.CDATA{Value: tag.Name}})
+		if err != nil {
+			return err
+		}
+	}
+
+	postID := 0
+	err = forEachExportArticle(ctx, export, func(article models.ArticleContent) error {
+		postID++
+		published := article.CreatedAt
+		if article.PublishedAt != nil {
+			published = *article.PublishedAt
+		}
+		status := "draft"
+		if article.Status == models.Published {
+			status = "publish"
+		}
+		link := siteURL + "/articles/" + article.Slug
+
+		item := libs.WXRItem{
+			Title:       article.Title,
+			Link:        link,
+			PubDate:     published.UTC().Format(time.RFC1123Z),
+			GUID:        libs.WXRGUID{IsPermaLink: "false", Value: article.ID.Hex()},
+			Content:     libs.CDATA{Value: article.ContentHTML},
+			Excerpt:     libs.CDATA{Value: article.Excerpt},
+			PostID:      postID,
+			PostDate:    published.UTC().Format(libs.WXRDateFormat),
+			PostDateGMT: published.UTC().Format(libs.WXRDateFormat),
+			Modified:    article.UpdatedAt.UTC().Format(libs.WXRDateFormat),
+			ModifiedGMT: article.UpdatedAt.UTC().Format(libs.WXRDateFormat),
+			Name:        article.Slug,
+			Status:      status,
+			Type:        "post",
+		}
+		for _, id := range article.ArticleCategories {
+			if category, ok := categoriesByID[id]; ok {
+				item.Categories = append(item.Categories, libs.WXRItemCategory{Domain: "category", Nicename: category.Slug, Name: category.Name})
+			}
+		}
+		for _, tag := range article.Tags {
+			item.Categories = append(item.Categories, libs.WXRItemCategory{Domain: "post_tag", Nicename: libs.Slugify(tag), Name: tag})
+		}
+
+		// The featured image is exported as an attachment that the post points to
+		var attachment *libs.WXRItem
+		if article.Image != "" {
+			imageURL := article.Image
+			if strings.HasPrefix(imageURL, "/") {
+				imageURL = export.baseURL + imageURL
+			}
+			postID++
+			attachment = &libs.WXRItem{
+				Title:         article.Title,
+				Link:          imageURL,
+				PubDate:       item.PubDate,
+				GUID:          libs.WXRGUID{IsPermaLink: "false", Value: imageURL},
+				PostID:        postID,
+				PostDate:      item.PostDate,
+				PostDateGMT:   item.PostDateGMT,
+				Modified:      item.Modified,
+				ModifiedGMT:   item.ModifiedGMT,
+				Name:          article.Slug + "-image",
+				Status:        "inherit",
+				ParentID:      item.PostID,
+				Type:          "attachment",
+				AttachmentURL: imageURL,
+			}
+			item.PostMeta = append(item.PostMeta, libs.WXRPostMeta{
+				Key:   libs.CDATA{Value: "_thumbnail_id"},
+				Value: libs.CDATA{Value: strconv.Itoa(attachment.PostID)},
+			})
+		}
+
+		if err := wxr.WriteItem(item); err != nil {
+			return err
+		}
+		if attachment != nil {
+			return wxr.WriteItem(*attachment)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return wxr.Close()
+}
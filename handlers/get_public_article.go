@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"myfiberproject/database"
+	"myfiberproject/models"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// GetPublicArticles lists published articles, newest first.
+// The "category" and "tag" query parameters filter by category and tag slug.
+func GetPublicArticles(c *fiber.Ctx) error {
+	db := database.GetMongoClient().Database(database.GetDatabaseName())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := publishedArticleFilter()
+
+	if categorySlug := c.Query("category"); categorySlug != "" {
+		var category models.ArticleCategory
+		if _, err := findBySlugOrID(ctx, db.Collection("article_category"), categorySlug, bson.M{}, &category); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Category not found"})
+		}
+		filter["article_categories"] = category.ID
+	}
+
+	if tagSlug := c.Query("tag"); tagSlug != "" {
+		var tag models.Tag
+		if err := tagCollection().FindOne(ctx, bson.M{"slug": tagSlug}).Decode(&tag); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Tag not found"})
+		}
+		filter["tags"] = tag.Name
+	}
+
+	contentCollection := db.Collection("article_content")
+	total, err := contentCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+
+	page := parsePagination(c)
+	findOptions := page.findOptions().SetSort(bson.D{{Key: "published_at", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := contentCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+
+	var articles []models.ArticleContent
+	if err := cursor.All(ctx, &articles); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error decoding data"})
+	}
+
+	publicArticles, err := toPublicArticles(ctx, articles)
+	if err != nil {
+		log.Println("Failed to resolve article categories:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(page.response(publicArticles, total))
+}
+
+// GetPublicArticle fetches a single published article by its slug or ID
+func GetPublicArticle(c *fiber.Ctx) error {
+	param := c.Params("slug")
+
+	contentCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_content")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var article models.ArticleContent
+	moved, err := findBySlugOrID(ctx, contentCollection, param, publishedArticleFilter(), &article)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Article not found"})
+	}
+
+	// Old slugs redirect to the current one
+	if moved {
+		return redirectToSlug(c, param, article.Slug)
+	}
+
+	publicArticles, err := toPublicArticles(ctx, []models.ArticleContent{article})
+	if err != nil {
+		log.Println("Failed to resolve article categories:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+
+	c.Set(fiber.HeaderLastModified, article.UpdatedAt.UTC().Format(http.TimeFormat))
+	return c.Status(fiber.StatusOK).JSON(publicArticles[0])
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"myfiberproject/database"
+	"myfiberproject/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetPublicCategories lists all article categories by name
+func GetPublicCategories(c *fiber.Ctx) error {
+	articleCategoryCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_category")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := articleCategoryCollection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+
+	var categories []models.ArticleCategory
+	if err := cursor.All(ctx, &categories); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error decoding data"})
+	}
+
+	publicCategories := []models.PublicCategory{}
+	for _, category := range categories {
+		publicCategories = append(publicCategories, toPublicCategory(category))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(publicCategories)
+}
+
+// GetPublicCategory fetches a single article category by its slug or ID
+func GetPublicCategory(c *fiber.Ctx) error {
+	param := c.Params("slug")
+
+	articleCategoryCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_category")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var category models.ArticleCategory
+	moved, err := findBySlugOrID(ctx, articleCategoryCollection, param, bson.M{}, &category)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Category not found"})
+	}
+
+	// Old slugs redirect to the current one
+	if moved {
+		return redirectToSlug(c, param, category.Slug)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(toPublicCategory(category))
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"myfiberproject/config"
+	"myfiberproject/database"
+	"myfiberproject/libs"
+	"myfiberproject/models"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const feedItemLimit = 50
+
+type feedFormat struct {
+	contentType string
+	render      func(libs.Feed) ([]byte, error)
+}
+
+var feedFormats = map[string]feedFormat{
+	"rss":  {contentType: "application/rss+xml; charset=utf-8", render: libs.RenderRSS},
+	"atom": {contentType: "application/atom+xml; charset=utf-8", render: libs.RenderAtom},
+	"json": {contentType: "application/feed+json; charset=utf-8", render: libs.RenderJSONFeed},
+}
+
+// GetPublicFeed serves the latest published articles as an RSS, Atom or JSON feed.
+// When a category slug is given, only articles in that category are included.
+func GetPublicFeed(c *fiber.Ctx) error {
+	format, ok := feedFormats[c.Params("format")]
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Unknown feed format"})
+	}
+
+	db := database.GetMongoClient().Database(database.GetDatabaseName())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	siteURL := publicSiteURL()
+	feed := libs.Feed{
+		Title:       config.GetEnv("PUBLIC_SITE_NAME", "Marno"),
+		Description: "Latest articles",
+		Link:        siteURL,
+		FeedURL:     c.BaseURL() + c.OriginalURL(),
+	}
+	filter := publishedArticleFilter()
+
+	if param := c.Params("slug"); param != "" {
+		var category models.ArticleCategory
+		moved, err := findBySlugOrID(ctx, db.Collection("article_category"), param, bson.M{}, &category)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Category not found"})
+		}
+		if moved {
+			return redirectToSlug(c, param, category.Slug)
+		}
+
+		filter["article_categories"] = category.ID
+		feed.Title += " - " + category.Name
+		feed.Description = "Latest articles in " + category.Name
+		feed.Link = siteURL + "/categories/" + category.Slug
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "published_at", Value: -1}}).SetLimit(feedItemLimit)
+	cursor, err := db.Collection("article_content").Find(ctx, filter, findOptions)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+	var articles []models.ArticleContent
+	if err := cursor.All(ctx, &articles); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error decoding data"})
+	}
+
+	publicArticles, err := toPublicArticles(ctx, articles)
+	if err != nil {
+		log.Println("Failed to resolve article categories:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+
+	for _, article := range publicArticles {
+		item := libs.FeedItem{
+			ID:        article.ID.Hex(),
+			Title:     article.Title,
+			Link:      siteURL + "/articles/" + article.Slug,
+			Summary:   article.Excerpt,
+			Content:   article.ContentHTML,
+			Image:     article.Image,
+			Published: article.UpdatedAt,
+			Updated:   article.UpdatedAt,
+		}
+		if article.PublishedAt != nil {
+			item.Published = *article.PublishedAt
+		}
+		for _, category := range article.Categories {
+			item.Categories = append(item.Categories, category.Name)
+		}
+		if article.UpdatedAt.After(feed.Updated) {
+			feed.Updated = article.UpdatedAt
+		}
+		feed.Items = append(feed.Items, item)
+	}
+
+	// Conditional requests are answered by the ETag of the public route group. If-Modified-Since is
+	// not: unpublishing the newest article moves Last-Modified back, and clients would keep it.
+	if !feed.Updated.IsZero() {
+		c.Set(fiber.HeaderLastModified, feed.Updated.UTC().Format(http.TimeFormat))
+	} else {
+		feed.Updated = time.Now()
+	}
+
+	body, err := format.render(feed)
+	if err != nil {
+		log.Println("Failed to render feed:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to render feed"})
+	}
+
+	c.Set(fiber.HeaderContentType, format.contentType)
+	return c.Status(fiber.StatusOK).Send(body)
+}
@@ -8,16 +8,12 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func GetArticleCtegoryByID(c *fiber.Ctx) error {
-	// Extracting the ID from the URL parameters
-	articleCategoryID, err := primitive.ObjectIDFromHex(c.Params("id"))
-	if err != nil {
-		// If the ID is not a valid ObjectId, return a Bad Request status
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID"})
-	}
+// GetArticleCategoryByID fetches a single category by its ID or slug
+func GetArticleCategoryByID(c *fiber.Ctx) error {
+	// Extracting the ID or slug from the URL parameters
+	param := c.Params("id")
 
 	// Fetch the database name dynamically
 	articleCategoryCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_category")
@@ -25,12 +21,18 @@ func GetArticleCtegoryByID(c *fiber.Ctx) error {
 	defer cancel()
 
 	var articleCategory models.ArticleCategory
-	// Finding the counterpart by its ID in the database
-	if err := articleCategoryCollection.FindOne(ctx, bson.M{"_id": articleCategoryID}).Decode(&articleCategory); err != nil {
+	// Finding the counterpart by its ID or slug in the database
+	moved, err := findBySlugOrID(ctx, articleCategoryCollection, param, bson.M{}, &articleCategory)
+	if err != nil {
 		// If no document is found, return a Not Found status
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Data not found"})
 	}
 
+	// Old slugs redirect to the current one
+	if moved {
+		return redirectToSlug(c, param, articleCategory.Slug)
+	}
+
 	// If data is found, return it with an OK status
 	return c.Status(fiber.StatusOK).JSON(articleCategory)
 }
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"context"
+	"myfiberproject/database"
+	"myfiberproject/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// GetArticleContentByID fetches a single article by its ID or slug
+func GetArticleContentByID(c *fiber.Ctx) error {
+	// Extracting the ID or slug from the URL parameters
+	param := c.Params("id")
+
+	// Fetch the database name dynamically
+	contentCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_content")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var articleContent models.ArticleContent
+	moved, err := findBySlugOrID(ctx, contentCollection, param, bson.M{}, &articleContent)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Data not found"})
+	}
+
+	// Old slugs redirect to the current one
+	if moved {
+		return redirectToSlug(c, param, articleContent.Slug)
+	}
+
+	renderArticleContent(&articleContent)
+	setVersionETag(c, articleContent.Version)
+	return c.Status(fiber.StatusOK).JSON(articleContent)
+}
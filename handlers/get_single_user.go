@@ -35,6 +35,7 @@ func GetSingleUser(c *fiber.Ctx) error {
 	}
 
 	user.Password = "" // Omit the password in the response for security
+	setVersionETag(c, user.Version)
 
 	return c.Status(fiber.StatusOK).JSON(user)
 }
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"myfiberproject/config"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetSitemap serves /sitemap.xml, either as a single sitemap or as a sitemap index
+func GetSitemap(c *fiber.Ctx) error {
+	return sendSitemap(c, sitemapCacheKey("root"), renderSitemapRoot)
+}
+
+// GetSitemapPage serves one of the sitemaps listed in the sitemap index
+func GetSitemapPage(c *fiber.Ctx) error {
+	page, err := c.ParamsInt("page")
+	if err != nil || page < 1 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Sitemap not found"})
+	}
+
+	return sendSitemap(c, sitemapCacheKey(fmt.Sprintf("page:%d", page)), func(ctx context.Context) ([]byte, error) {
+		return renderSitemapPage(ctx, int64(page))
+	})
+}
+
+// sendSitemap serves a rendered sitemap document from the cache, rendering it on a miss
+func sendSitemap(c *fiber.Ctx, cacheKey string, render func(ctx context.Context) ([]byte, error)) error {
+	c.Set(fiber.HeaderContentType, "application/xml; charset=utf-8")
+
+	if cached, found := config.CacheInstance.Get(cacheKey); found {
+		return c.Status(fiber.StatusOK).Send(cached.([]byte))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	body, err := render(ctx)
+	if errors.Is(err, errSitemapPageNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Sitemap not found"})
+	}
+	if err != nil {
+		log.Println("Failed to render sitemap:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to render sitemap"})
+	}
+
+	config.CacheInstance.SetDefault(cacheKey, body)
+	return c.Status(fiber.StatusOK).Send(body)
+}
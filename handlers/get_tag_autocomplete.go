@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+	"myfiberproject/libs"
+	"myfiberproject/models"
+	"regexp"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultAutocompleteLimit = 10
+	maxAutocompleteLimit     = 50
+)
+
+// AutocompleteTags returns the most used tags whose name starts with the "q" query parameter
+func AutocompleteTags(c *fiber.Ctx) error {
+	prefix := libs.Slugify(c.Query("q"))
+	if prefix == "" {
+		return c.Status(fiber.StatusOK).JSON([]models.Tag{})
+	}
+
+	limit := c.QueryInt("limit", defaultAutocompleteLimit)
+	if limit <= 0 || limit > maxAutocompleteLimit {
+		limit = defaultAutocompleteLimit
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Matching on the slug keeps the lookup case and accent insensitive and lets it use the slug index
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "usage_count", Value: -1}, {Key: "name", Value: 1}}).
+		SetLimit(int64(limit))
+	cursor, err := tagCollection().Find(ctx, bson.M{"slug": bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)}}, findOptions)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+
+	tags := []models.Tag{}
+	if err := cursor.All(ctx, &tags); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error decoding data"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(tags)
+}
@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"myfiberproject/config"
+	"myfiberproject/database"
+	"myfiberproject/libs"
+	"myfiberproject/models"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	errInvitationInvalid = errors.New("invalid or expired invitation")
+	errEmailInUse        = errors.New("email is already in use")
+	errFullNameRequired  = errors.New("full_name is required")
+)
+
+func invitationCollection() *mongo.Collection {
+	return database.GetMongoClient().Database(database.GetDatabaseName()).Collection("invitations")
+}
+
+func invitationTTL() time.Duration {
+	return time.Duration(config.GetEnvInt("INVITATION_TTL_HOURS", 168)) * time.Hour
+}
+
+type InvitationRequest struct {
+	Email    string      `json:"email" validate:"required,email"`
+	FullName string      `json:"full_name"`
+	Role     models.Role `json:"role" validate:"required,oneof=po it comms hr cmas viewer"`
+}
+
+type AcceptInvitationRequest struct {
+	Token              string `json:"token" validate:"required"`
+	FullName           string `json:"full_name"` // Defaults to the name given by the administrator
+	Password           string `json:"password" validate:"required,min=8"`
+	TermsAndConditions bool   `json:"terms_and_conditions" validate:"required"`
+}
+
+// sendInvitationEmail emails the invitee a link to accept the invitation
+func sendInvitationEmail(ctx context.Context, invitation models.Invitation, token string) error {
+	link := frontendLink("/accept-invitation", url.Values{"token": {token}})
+	greeting := "Hello"
+	if invitation.FullName != "" {
+		greeting += " " + invitation.FullName
+	}
+	return sendMail(ctx, libs.Mail{
+		To:      invitation.Email,
+		Subject: "You have been invited",
+		Text: fmt.Sprintf("%s,\n\nYou have been invited to join with the %s role. Use the link below to choose your password and activate your account. It expires on %s.\n\n%s\n\nIf you were not expecting this invitation, you can ignore this email.\n",
+			greeting, invitation.Role, invitation.ExpiresAt.Format("2 January 2006 15:04 MST"), link),
+	})
+}
+
+// invitationFromParams loads the invitation named by the :id route parameter
+func invitationFromParams(ctx context.Context, c *fiber.Ctx) (*models.Invitation, error) {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid invitation ID"})
+	}
+	var invitation models.Invitation
+	if err := invitationCollection().FindOne(ctx, bson.M{"_id": id}).Decode(&invitation); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Invitation not found"})
+		}
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching invitation"})
+	}
+	return &invitation, nil
+}
+
+// CreateInvitation invites a colleague by email with a pre-assigned role
+func CreateInvitation(c *fiber.Ctx) error {
+	var request InvitationRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+	}
+	if validationErr := validate.Struct(&request); validationErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": validationErr.Error()})
+	}
+
+	invitedBy, err := currentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	userCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("users")
+	count, err := userCollection.CountDocuments(ctx, bson.M{"email": request.Email})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+	if count > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Email is already in use"})
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create invitation token"})
+	}
+
+	now := time.Now()
+	invitation := models.Invitation{
+		ID:        primitive.NewObjectID(),
+		Email:     request.Email,
+		FullName:  request.FullName,
+		Role:      request.Role,
+		Status:    models.InvitationPending,
+		TokenHash: hashToken(token),
+		InvitedBy: invitedBy,
+		SentAt:    now,
+		CreatedAt: now,
+		ExpiresAt: now.Add(invitationTTL()),
+	}
+
+	// A unique index allows one pending invitation per address
+	if _, err := invitationCollection().InsertOne(ctx, invitation); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "An invitation is already pending for this email, resend or revoke it instead"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Cannot insert invitation"})
+	}
+
+	recordAudit(ctx, c, models.AuditInvitationCreated, invitation.ID, map[string]interface{}{
+		"email": invitation.Email,
+		"role":  invitation.Role,
+	})
+
+	response := fiber.Map{"invitation": invitation}
+	if err := sendInvitationEmail(ctx, invitation, token); err != nil {
+		log.Printf("Failed to send invitation email to %s: %v", invitation.Email, err)
+		response["warning"] = "The invitation email could not be sent, resend the invitation"
+	}
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// GetInvitations lists invitations, newest first, optionally filtered by status
+func GetInvitations(c *fiber.Ctx) error {
+	filter := bson.M{}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := invitationCollection()
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+
+	page := parsePagination(c)
+	cursor, err := collection.Find(ctx, filter, page.findOptions().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+
+	invitations := []models.Invitation{}
+	if err := cursor.All(ctx, &invitations); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error decoding data"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(page.response(invitations, total))
+}
+
+// ResendInvitation emails a pending invitation again with a new token and expiry. The previous
+// link stops working.
+func ResendInvitation(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	invitation, err := invitationFromParams(ctx, c)
+	if invitation == nil {
+		return err
+	}
+	if invitation.Status != models.InvitationPending {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Only pending invitations can be resent"})
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create invitation token"})
+	}
+
+	now := time.Now()
+	err = invitationCollection().FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": invitation.ID, "status": models.InvitationPending},
+		bson.M{"$set": bson.M{"token_hash": hashToken(token), "sent_at": now, "expires_at": now.Add(invitationTTL())}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(invitation)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Only pending invitations can be resent"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating invitation"})
+	}
+
+	recordAudit(ctx, c, models.AuditInvitationResent, invitation.ID, map[string]interface{}{"email": invitation.Email})
+
+	if err := sendInvitationEmail(ctx, *invitation, token); err != nil {
+		log.Printf("Failed to send invitation email to %s: %v", invitation.Email, err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "The invitation email could not be sent"})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"invitation": invitation})
+}
+
+// RevokeInvitation cancels a pending invitation so its link can no longer be used
+func RevokeInvitation(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	invitation, err := invitationFromParams(ctx, c)
+	if invitation == nil {
+		return err
+	}
+
+	result, err := invitationCollection().UpdateOne(
+		ctx,
+		bson.M{"_id": invitation.ID, "status": models.InvitationPending},
+		bson.M{"$set": bson.M{"status": models.InvitationRevoked, "revoked_at": time.Now()}},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating invitation"})
+	}
+	if result.MatchedCount == 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Only pending invitations can be revoked"})
+	}
+
+	recordAudit(ctx, c, models.AuditInvitationRevoked, invitation.ID, map[string]interface{}{"email": invitation.Email})
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Invitation revoked"})
+}
+
+// pendingInvitationFilter matches the usable invitation with the given token
+func pendingInvitationFilter(token string) bson.M {
+	return bson.M{
+		"token_hash": hashToken(token),
+		"status":     models.InvitationPending,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+}
+
+// GetInvitationByToken shows the invitee which address and role an invitation is for
+func GetInvitationByToken(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "token is required"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var invitation models.Invitation
+	err := invitationCollection().FindOne(ctx, pendingInvitationFilter(token)).Decode(&invitation)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": errInvitationInvalid.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching invitation"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"email":      invitation.Email,
+		"full_name":  invitation.FullName,
+		"role":       invitation.Role,
+		"expires_at": invitation.ExpiresAt,
+	})
+}
+
+// AcceptInvitation creates an approved user with the invited role and the chosen password, and
+// logs them in. Opening the emailed link proves the address, so it counts as verified.
+func AcceptInvitation(c *fiber.Ctx) error {
+	var request AcceptInvitationRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+	}
+	if validationErr := validate.Struct(&request); validationErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": validationErr.Error()})
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error hashing password"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	userCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("users")
+	var user models.User
+	var invitation models.Invitation
+
+	// Claim the invitation and create the user together, so a failure leaves the invitation usable
+	err = database.WithTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		now := time.Now()
+		user = models.User{
+			ID:                 primitive.NewObjectID(),
+			Password:           string(hashedPassword),
+			Status:             models.Approved,
+			TermsAndConditions: request.TermsAndConditions,
+			EmailVerifiedAt:    &now,
+			Version:            1,
+			CreatedAt:          now,
+			UpdatedAt:          now,
+		}
+
+		err := invitationCollection().FindOneAndUpdate(
+			sessCtx,
+			pendingInvitationFilter(request.Token),
+			bson.M{"$set": bson.M{"status": models.InvitationAccepted, "accepted_at": now, "user_id": user.ID}},
+		).Decode(&invitation)
+		if err == mongo.ErrNoDocuments {
+			return errInvitationInvalid
+		}
+		if err != nil {
+			return err
+		}
+
+		user.Email = invitation.Email
+		user.Role = invitation.Role
+		user.FullName = request.FullName
+		if user.FullName == "" {
+			user.FullName = invitation.FullName
+		}
+		if user.FullName == "" {
+			return errFullNameRequired
+		}
+
+		count, err := userCollection.CountDocuments(sessCtx, bson.M{"email": user.Email})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return errEmailInUse
+		}
+
+		_, err = userCollection.InsertOne(sessCtx, user)
+		return err
+	})
+	switch {
+	case errors.Is(err, errInvitationInvalid):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": errInvitationInvalid.Error()})
+	case errors.Is(err, errEmailInUse):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Email is already in use"})
+	case errors.Is(err, errFullNameRequired):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": errFullNameRequired.Error()})
+	case err != nil:
+		log.Printf("Error accepting invitation: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Cannot accept invitation"})
+	}
+
+	recordAudit(ctx, c, models.AuditInvitationAccepted, user.ID, map[string]interface{}{
+		"invitation_id": invitation.ID,
+		"email":         user.Email,
+		"role":          user.Role,
+	})
+
+	tokens, err := startSession(ctx, c, user, false, false)
+	if err != nil {
+		log.Printf("Error issuing session tokens: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error issuing tokens"})
+	}
+
+	user.Password = ""
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"user":          user,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
@@ -4,16 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"log"
-	"os"
 	"regexp"
 	"strconv"
 	"time"
 
 	"myfiberproject/database"
+	"myfiberproject/middleware"
 	"myfiberproject/models"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v4"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
@@ -87,39 +86,29 @@ func Login(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Incorrect password"})
 	}
 
-	var claims jwt.MapClaims
-	if loginRequest.RememberMe != nil && *loginRequest.RememberMe {
-		claims = jwt.MapClaims{
-			"id":     user.ID.Hex(),
-			"role":   user.Role,
-			"status": user.Status,
-		}
-	} else {
-		expTime := time.Now().Add(72 * time.Hour) // 3 days expiration
-		claims = jwt.MapClaims{
-			"id":     user.ID.Hex(),
-			"role":   user.Role,
-			"status": user.Status,
-			"exp":    expTime.Unix(),
-		}
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	// Access tokens are always short-lived; RememberMe only extends the refresh token lifetime
+	rememberMe := loginRequest.RememberMe != nil && *loginRequest.RememberMe
 
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		log.Println("JWT_SECRET is not set in environment variables")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "JWT_SECRET environment variable not set"})
+	// Users with two-factor authentication finish logging in at POST /login/mfa
+	if user.MFAEnabledAt != nil {
+		return mfaChallengeResponse(c, user, rememberMe)
 	}
 
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	tokens, err := startSession(ctx, c, user, rememberMe, false)
 	if err != nil {
-		log.Printf("Error signing token: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error signing token"})
+		log.Printf("Error issuing session tokens: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error issuing tokens"})
 	}
 
 	log.Printf("User %s logged in with token; RememberMe is set to: %s", loginRequest.Email, rememberMeStatus)
 
 	user.Password = "" // Omit the password for security
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"user": user, "token": tokenString})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"user":          user,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		// The session can only use the role once the user has enrolled and logged in again
+		"mfa_enrollment_required": middleware.MFARequired(string(user.Role)),
+	})
 }
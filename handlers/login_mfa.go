@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"myfiberproject/config"
+	"myfiberproject/database"
+	"myfiberproject/models"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// When a user with MFA enabled logs in with their password, Login returns a short-lived challenge
+// token instead of a session. The challenge is exchanged for a session at POST /login/mfa together
+// with a TOTP or recovery code. Like verification tokens, challenges are JWTs signed with a key
+// derived from JWT_SECRET.
+
+const mfaChallengeAudience = "mfa-challenge"
+
+// maxMFAAttempts limits the codes that can be tried for a user within mfaAttemptWindow, whatever
+// the challenge or session they are sent with
+const maxMFAAttempts = 5
+
+const mfaAttemptWindow = 15 * time.Minute
+
+var errMFALocked = errors.New("too many attempts, try again later")
+
+type mfaChallengeClaims struct {
+	RememberMe bool `json:"remember_me,omitempty"`
+	jwt.RegisteredClaims
+}
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	SecondFactorRequest
+}
+
+func mfaChallengeKey() ([]byte, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil, errors.New("JWT_SECRET environment variable not set")
+	}
+	return []byte(mfaChallengeAudience + ":" + secret), nil
+}
+
+func mfaChallengeTTL() time.Duration {
+	return time.Duration(config.GetEnvInt("MFA_CHALLENGE_TTL_MINUTES", 5)) * time.Minute
+}
+
+// issueMFAChallenge signs a challenge for a user who passed the first factor
+func issueMFAChallenge(user models.User, rememberMe bool) (string, error) {
+	key, err := mfaChallengeKey()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, mfaChallengeClaims{
+		RememberMe: rememberMe,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        primitive.NewObjectID().Hex(),
+			Subject:   user.ID.Hex(),
+			Audience:  jwt.ClaimStrings{mfaChallengeAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(mfaChallengeTTL())),
+		},
+	}).SignedString(key)
+}
+
+// parseMFAChallenge validates a challenge token and returns its claims
+func parseMFAChallenge(tokenString string) (*mfaChallengeClaims, error) {
+	key, err := mfaChallengeKey()
+	if err != nil {
+		return nil, err
+	}
+	var claims mfaChallengeClaims
+	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return key, nil
+	})
+	if err != nil || !token.Valid || !claims.VerifyAudience(mfaChallengeAudience, true) || claims.ID == "" {
+		return nil, errors.New("invalid or expired MFA challenge")
+	}
+	return &claims, nil
+}
+
+// mfaChallengeResponse is sent by Login in place of the session tokens
+func mfaChallengeResponse(c *fiber.Ctx, user models.User, rememberMe bool) error {
+	challenge, err := issueMFAChallenge(user, rememberMe)
+	if err != nil {
+		log.Printf("Error issuing MFA challenge: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error issuing tokens"})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"mfa_required": true,
+		"mfa_token":    challenge,
+		"expires_in":   int(mfaChallengeTTL().Seconds()),
+	})
+}
+
+// VerifyLoginMFA completes a login that was answered with an MFA challenge
+func VerifyLoginMFA(c *fiber.Ctx) error {
+	var request MFALoginRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+	}
+	if validationErr := validate.Struct(&request); validationErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": validationErr.Error()})
+	}
+	if request.Code == "" && request.RecoveryCode == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "code or recovery_code is required"})
+	}
+
+	claims, err := parseMFAChallenge(request.MFAToken)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.Subject)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA challenge"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	if err := usersCollection().FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA challenge"})
+	}
+
+	if err := verifySecondFactor(ctx, c, user, request.SecondFactorRequest); err != nil {
+		return secondFactorError(c, err)
+	}
+
+	tokens, err := startSession(ctx, c, user, claims.RememberMe, true)
+	if err != nil {
+		log.Printf("Error issuing session tokens: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error issuing tokens"})
+	}
+
+	user.Password = ""
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"user":          user,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+func mfaAttemptsCollection() *mongo.Collection {
+	return database.GetMongoClient().Database(database.GetDatabaseName()).Collection("mfa_attempts")
+}
+
+func mfaLockout() time.Duration {
+	return time.Duration(config.GetEnvInt("MFA_LOCKOUT_MINUTES", 15)) * time.Minute
+}
+
+// registerMFAAttempt counts a second factor attempt for a user before the code is checked, so that
+// parallel requests cannot get past the limit. Once the limit is passed the user is locked out.
+// Attempts made during a lockout still count, so that a client that keeps guessing is locked out
+// again as soon as the lockout ends.
+func registerMFAAttempt(ctx context.Context, userID primitive.ObjectID) error {
+	now := time.Now()
+	var attempts models.MFAAttempts
+	err := mfaAttemptsCollection().FindOneAndUpdate(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$inc": bson.M{"attempts": 1}, "$setOnInsert": bson.M{"expires_at": now.Add(mfaAttemptWindow)}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&attempts)
+	if err != nil {
+		return err
+	}
+	if attempts.LockedUntil != nil && attempts.LockedUntil.After(now) {
+		return errMFALocked
+	}
+	if attempts.Attempts <= maxMFAAttempts {
+		return nil
+	}
+
+	lockedUntil := now.Add(mfaLockout())
+	_, err = mfaAttemptsCollection().UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{
+		"attempts":     0,
+		"locked_until": lockedUntil,
+		"expires_at":   lockedUntil.Add(mfaAttemptWindow),
+	}})
+	if err != nil {
+		return err
+	}
+	log.Printf("Second factor locked for user %s until %s", userID.Hex(), lockedUntil.Format(time.RFC3339))
+	return errMFALocked
+}
+
+// clearMFAAttempts resets the count after a successful second factor
+func clearMFAAttempts(ctx context.Context, userID primitive.ObjectID) {
+	if _, err := mfaAttemptsCollection().DeleteOne(ctx, bson.M{"_id": userID}); err != nil {
+		log.Println("Failed to clear MFA attempts:", err)
+	}
+}
+
+// secondFactorError sends the response for an error of verifySecondFactor
+func secondFactorError(c *fiber.Ctx, err error) error {
+	switch err {
+	case errSecondFactorInvalid:
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	case errMFALocked:
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(mfaLockout().Seconds())))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": err.Error()})
+	}
+	log.Println("Failed to verify second factor:", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify code"})
+}
@@ -3,21 +3,66 @@
 package handlers // handlers: package for request handlers
 
 import (
-	"github.com/go-playground/validator/v10" // validator: package for validating structs
-	"github.com/gofiber/fiber/v2"            // fiber: package for building web APIs
+	"context" // context: package for request deadlines
+	"log"     // log: package for logging
+	"time"    // time: package for time related operations
+
+	"myfiberproject/middleware" // middleware: package for token revocation
+
+	"github.com/go-playground/validator/v10"     // validator: package for validating structs
+	"github.com/gofiber/fiber/v2"                // fiber: package for building web APIs
+	"go.mongodb.org/mongo-driver/bson/primitive" // primitive: package for ObjectID from MongoDB
 )
 
 // Initialize the validator
 // This is a global variable that can be accessed from any function
 var validate = validator.New()
 
-// Logout handles the user logout process
+// Logout ends the current session: the access token used for the request, the other access tokens
+// of the session and its refresh tokens are revoked.
 func Logout(c *fiber.Ctx) error {
-	// Since JWTs are stateless, there's no server-side action needed to "invalidate" the token.
-	// You can set a flag in the response that tells the client to clear the token.
+	claims, err := currentClaims(c) // Claims of the token used for the request
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := middleware.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil { // Revoke the token itself
+		log.Println("Failed to revoke token:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to log out"})
+	}
+	if sessionID, err := primitive.ObjectIDFromHex(claims.SessionID); err == nil { // Revoke the rest of the session
+		if err := endSession(ctx, sessionID); err != nil {
+			log.Println("Failed to end session:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to log out"})
+		}
+	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{ // Return a success response
 		"message":    "Logged out successfully", //	Message for successful logout
 		"clearToken": true,                      // Flag to clear the token
 	})
 }
+
+// LogoutEverywhere ends every session of the current user, on all devices
+func LogoutEverywhere(c *fiber.Ctx) error {
+	userID, err := currentUserID(c) // ID of the user making the request
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := endAllSessions(ctx, userID); err != nil {
+		log.Println("Failed to end sessions:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to log out"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{ // Return a success response
+		"message":    "Logged out of all sessions", // Message for successful logout
+		"clearToken": true,                         // Flag to clear the token
+	})
+}
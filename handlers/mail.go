@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+	"myfiberproject/config"
+	"myfiberproject/libs"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+var (
+	mailerInstance libs.Mailer
+	mailerErr      error
+	mailerOnce     sync.Once
+)
+
+// sendMail delivers a message with the mailer selected by MAIL_DRIVER
+func sendMail(ctx context.Context, mail libs.Mail) error {
+	mailerOnce.Do(func() {
+		mailerInstance, mailerErr = libs.NewMailer(libs.MailerConfig{
+			Driver:       config.GetEnv("MAIL_DRIVER", "log"),
+			From:         config.GetEnv("MAIL_FROM", ""),
+			SMTPHost:     config.GetEnv("SMTP_HOST", ""),
+			SMTPPort:     config.GetEnv("SMTP_PORT", "587"),
+			SMTPUsername: config.GetEnv("SMTP_USERNAME", ""),
+			SMTPPassword: config.GetEnv("SMTP_PASSWORD", ""),
+		})
+	})
+	if mailerErr != nil {
+		return mailerErr
+	}
+	return mailerInstance.Send(ctx, mail)
+}
+
+// frontendLink builds a link to a page of the frontend, which is where emails send users
+func frontendLink(path string, query url.Values) string {
+	link := strings.TrimRight(config.GetEnv("FRONTEND_URL", "http://localhost:3000"), "/") + path
+	if len(query) > 0 {
+		link += "?" + query.Encode()
+	}
+	return link
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"myfiberproject/config"
+	"myfiberproject/database"
+	"myfiberproject/models"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxMediaDownloadSize limits images downloaded from other sites
+const maxMediaDownloadSize = 20 * 1024 * 1024
+
+// mediaExtensions lists the image types accepted in media storage. SVG is left out on purpose
+// as it can carry scripts.
+var mediaExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// MediaDir returns the directory media files are stored in
+func MediaDir() string {
+	return config.GetEnv("MEDIA_DIR", "uploads")
+}
+
+func mediaBaseURL() string {
+	return strings.TrimRight(config.GetEnv("MEDIA_BASE_URL", "/media"), "/")
+}
+
+// storeMedia writes an image to media storage and records it in the media collection
+func storeMedia(ctx context.Context, fileName string, data []byte, source string) (models.Media, error) {
+	contentType := http.DetectContentType(data)
+	extension, ok := mediaExtensions[contentType]
+	if !ok {
+		return models.Media{}, fmt.Errorf("unsupported media type %s for %s", contentType, fileName)
+	}
+
+	media := models.Media{
+		ID:          primitive.NewObjectID(),
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Source:      source,
+		CreatedAt:   time.Now(),
+	}
+	media.Path = media.ID.Hex() + extension
+	media.URL = mediaBaseURL() + "/" + media.Path
+
+	if err := os.MkdirAll(MediaDir(), 0o755); err != nil {
+		return models.Media{}, fmt.Errorf("failed to create media directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(MediaDir(), media.Path), data, 0o644); err != nil {
+		return models.Media{}, fmt.Errorf("failed to write media file: %w", err)
+	}
+
+	mediaCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("media")
+	if _, err := mediaCollection.InsertOne(ctx, media); err != nil {
+		os.Remove(filepath.Join(MediaDir(), media.Path))
+		return models.Media{}, fmt.Errorf("failed to insert media: %w", err)
+	}
+
+	return media, nil
+}
+
+// errForbiddenMediaHost is returned for media URLs that point into the server's own network
+var errForbiddenMediaHost = errors.New("media URL points to a private or local address")
+
+// mediaClient downloads media from other sites. The address is checked after DNS resolution and
+// for every redirect, so that an import cannot make the server fetch internal services.
+var mediaClient = &http.Client{
+	Timeout: 30 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip, err := netip.ParseAddr(host); err != nil || !isPublicIP(ip) {
+					return errForbiddenMediaHost
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 20 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
+		}
+		return checkMediaURL(req.URL)
+	},
+}
+
+// nonPublicPrefixes are the blocks of the IANA IPv4 and IPv6 special-purpose address registries that
+// are not globally reachable, plus multicast and the reserved 240.0.0.0/4. Translation prefixes such as
+// 64:ff9b::/96 and 2002::/16 are included as they can reach IPv4 addresses of any kind.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "This network"
+	netip.MustParsePrefix("10.0.0.0/8"),      // Private use
+	netip.MustParsePrefix("100.64.0.0/10"),   // Shared address space (carrier-grade NAT)
+	netip.MustParsePrefix("127.0.0.0/8"),     // Loopback
+	netip.MustParsePrefix("169.254.0.0/16"),  // Link local, including cloud metadata services
+	netip.MustParsePrefix("172.16.0.0/12"),   // Private use
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // Documentation (TEST-NET-1)
+	netip.MustParsePrefix("192.88.99.0/24"),  // Deprecated 6to4 relay anycast
+	netip.MustParsePrefix("192.168.0.0/16"),  // Private use
+	netip.MustParsePrefix("198.18.0.0/15"),   // Benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // Documentation (TEST-NET-2)
+	netip.MustParsePrefix("203.0.113.0/24"),  // Documentation (TEST-NET-3)
+	netip.MustParsePrefix("224.0.0.0/4"),     // Multicast
+	netip.MustParsePrefix("240.0.0.0/4"),     // Reserved, including the limited broadcast address
+	netip.MustParsePrefix("::/128"),          // Unspecified
+	netip.MustParsePrefix("::1/128"),         // Loopback
+	netip.MustParsePrefix("::ffff:0:0/96"),   // IPv4-mapped, in case an address was not unmapped
+	netip.MustParsePrefix("64:ff9b::/96"),    // IPv4/IPv6 translation
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // Local-use IPv4/IPv6 translation
+	netip.MustParsePrefix("100::/64"),        // Discard only
+	netip.MustParsePrefix("2001::/23"),       // IETF protocol assignments, including Teredo
+	netip.MustParsePrefix("2001:db8::/32"),   // Documentation
+	netip.MustParsePrefix("2002::/16"),       // 6to4
+	netip.MustParsePrefix("3fff::/20"),       // Documentation
+	netip.MustParsePrefix("5f00::/16"),       // Segment routing SIDs
+	netip.MustParsePrefix("fc00::/7"),        // Unique local
+	netip.MustParsePrefix("fe80::/10"),       // Link local
+	netip.MustParsePrefix("ff00::/8"),        // Multicast
+}
+
+// isPublicIP reports whether ip is a globally routable unicast address. IPv4 addresses written as
+// IPv6 (::ffff:a.b.c.d) are checked as IPv4.
+func isPublicIP(ip netip.Addr) bool {
+	ip = ip.Unmap().WithZone("")
+	if !ip.IsValid() || !ip.IsGlobalUnicast() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkMediaURL only lets http and https URLs through
+func checkMediaURL(mediaURL *url.URL) error {
+	if mediaURL.Scheme != "http" && mediaURL.Scheme != "https" {
+		return fmt.Errorf("unsupported media URL scheme %q", mediaURL.Scheme)
+	}
+	if mediaURL.Hostname() == "" {
+		return errors.New("media URL has no host")
+	}
+	return nil
+}
+
+// downloadMedia fetches an image hosted elsewhere. Only public http and https addresses are fetched.
+func downloadMedia(mediaURL string) ([]byte, error) {
+	parsed, err := url.Parse(mediaURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid media URL: %w", err)
+	}
+	if err := checkMediaURL(parsed); err != nil {
+		return nil, err
+	}
+
+	resp, err := mediaClient.Get(parsed.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxMediaDownloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxMediaDownloadSize {
+		return nil, fmt.Errorf("image is larger than %d bytes", maxMediaDownloadSize)
+	}
+	return data, nil
+}
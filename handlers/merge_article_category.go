@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"myfiberproject/database"
+	"myfiberproject/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MergeArticleCategoryRequest struct {
+	TargetID string `json:"target_id" validate:"required"`
+	Name     string `json:"name"` // Optional new name for the merged category
+}
+
+// MergeArticleCategory merges the category in the URL into the target category.
+// Articles are moved to the target and the source category is removed in a single transaction.
+func MergeArticleCategory(c *fiber.Ctx) error {
+	// Parse the ID from the URL parameter
+	sourceID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	var req MergeArticleCategoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse request"})
+	}
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": validationErr.Error()})
+	}
+
+	targetID, err := primitive.ObjectIDFromHex(req.TargetID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid target ID"})
+	}
+	if targetID == sourceID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot merge a category into itself"})
+	}
+
+	articleCategoryCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_category")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var articlesUpdated int64
+	var merged models.ArticleCategory
+	err = database.WithTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		articlesUpdated, err = replaceArticleCategory(sessCtx, sourceID, targetID)
+		if err != nil {
+			return err
+		}
+
+		if err := articleCategoryCollection.FindOne(sessCtx, bson.M{"_id": targetID}).Decode(&merged); err != nil {
+			return err
+		}
+		if req.Name == "" || req.Name == merged.Name {
+			return nil
+		}
+
+		// Renaming the merged category also moves it to a new slug
+		slug, err := uniqueSlug(sessCtx, articleCategoryCollection, req.Name, targetID)
+		if err != nil {
+			return err
+		}
+		merged.PreviousSlugs = nextPreviousSlugs(merged.PreviousSlugs, merged.Slug, slug)
+		merged.Name = req.Name
+		merged.Slug = slug
+		merged.UpdatedAt = time.Now()
+
+		_, err = articleCategoryCollection.UpdateOne(sessCtx, bson.M{"_id": targetID}, bson.M{
+			"$set": bson.M{
+				"name":           merged.Name,
+				"slug":           merged.Slug,
+				"previous_slugs": merged.PreviousSlugs,
+				"updated_at":     merged.UpdatedAt,
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return articleCategoryReplaceError(c, err)
+	}
+
+	removeSitemapEntry(ctx, sourceID)
+	syncCategorySitemapEntry(ctx, merged)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":          "Article categories merged successfully",
+		"data":             merged,
+		"articles_updated": articlesUpdated,
+	})
+}
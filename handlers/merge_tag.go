@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"myfiberproject/database"
+	"myfiberproject/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MergeTagRequest struct {
+	TargetID string `json:"target_id" validate:"required"`
+}
+
+// MergeTag merges the tag in the URL into the target tag and removes it
+func MergeTag(c *fiber.Ctx) error {
+	// Parse the ID from the URL parameter
+	sourceID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	var req MergeTagRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse request"})
+	}
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": validationErr.Error()})
+	}
+
+	targetID, err := primitive.ObjectIDFromHex(req.TargetID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid target ID"})
+	}
+	if targetID == sourceID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot merge a tag into itself"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var source, target models.Tag
+	if err := tagCollection().FindOne(ctx, bson.M{"_id": sourceID}).Decode(&source); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Tag not found"})
+	}
+	if err := tagCollection().FindOne(ctx, bson.M{"_id": targetID}).Decode(&target); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Target tag not found"})
+	}
+
+	contentCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_content")
+	err = database.WithTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		// $addToSet and $pull cannot target the same field in one update, so add the target first
+		filter := bson.M{"tags": source.Name}
+		_, err := contentCollection.UpdateMany(sessCtx, filter, bson.M{
+			"$addToSet": bson.M{"tags": target.Name},
+			"$set":      bson.M{"updated_at": time.Now()},
+			"$inc":      incrementVersion,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := contentCollection.UpdateMany(sessCtx, filter, bson.M{"$pull": bson.M{"tags": source.Name}}); err != nil {
+			return err
+		}
+		if _, err := tagCollection().DeleteOne(sessCtx, bson.M{"_id": sourceID}); err != nil {
+			return err
+		}
+		return refreshTagUsage(sessCtx, []string{target.Name})
+	})
+	if err != nil {
+		log.Println("Failed to merge tags:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to merge tags"})
+	}
+
+	if err := tagCollection().FindOne(ctx, bson.M{"_id": targetID}).Decode(&target); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Tags merged successfully",
+		"data":    target,
+	})
+}
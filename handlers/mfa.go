@@ -0,0 +1,433 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"myfiberproject/config"
+	"myfiberproject/database"
+	"myfiberproject/libs"
+	"myfiberproject/middleware"
+	"myfiberproject/models"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Users protect their account with a TOTP authenticator app. Enrollment stores a pending secret
+// that only becomes active once the first code from the app is confirmed. TOTP secrets are
+// encrypted at rest with MFA_ENCRYPTION_KEY (JWT_SECRET by default); recovery codes are stored as
+// SHA-256 hashes and each can be used once.
+
+const recoveryCodeCount = 10
+
+var errSecondFactorInvalid = errors.New("invalid authentication code")
+
+type SecondFactorRequest struct {
+	Code         string `json:"code"`          // Current code of the authenticator app
+	RecoveryCode string `json:"recovery_code"` // One of the recovery codes, instead of Code
+}
+
+func usersCollection() *mongo.Collection {
+	return database.GetMongoClient().Database(database.GetDatabaseName()).Collection("users")
+}
+
+func mfaEncryptionKey() string {
+	return config.GetEnv("MFA_ENCRYPTION_KEY", os.Getenv("JWT_SECRET"))
+}
+
+func mfaIssuer() string {
+	return config.GetEnv("MFA_ISSUER", config.GetEnv("PUBLIC_SITE_NAME", "Marno"))
+}
+
+// normalizeRecoveryCode ignores case, spaces and dashes so codes can be typed as printed
+func normalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+}
+
+// generateRecoveryCodes returns new recovery codes and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		secret, err := libs.GenerateTOTPSecret() // 32 base32 characters; 10 of them are plenty
+		if err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(secret[:5] + "-" + secret[5:10])
+		codes = append(codes, code)
+		hashes = append(hashes, hashToken(normalizeRecoveryCode(code)))
+	}
+	return codes, hashes, nil
+}
+
+// verifySecondFactor checks a TOTP code or consumes a recovery code of a user with MFA enabled.
+// Accepted TOTP codes cannot be used again, and used recovery codes are removed. Attempts are
+// limited per user, see registerMFAAttempt.
+func verifySecondFactor(ctx context.Context, c *fiber.Ctx, user models.User, request SecondFactorRequest) error {
+	if user.MFAEnabledAt == nil {
+		return errSecondFactorInvalid
+	}
+	if err := registerMFAAttempt(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := checkSecondFactor(ctx, c, user, request); err != nil {
+		return err
+	}
+	clearMFAAttempts(ctx, user.ID)
+	return nil
+}
+
+// checkSecondFactor does the work of verifySecondFactor
+func checkSecondFactor(ctx context.Context, c *fiber.Ctx, user models.User, request SecondFactorRequest) error {
+
+	if request.RecoveryCode != "" {
+		hash := hashToken(normalizeRecoveryCode(request.RecoveryCode))
+		result, err := usersCollection().UpdateOne(ctx,
+			bson.M{"_id": user.ID, "recovery_codes": hash},
+			bson.M{"$pull": bson.M{"recovery_codes": hash}, "$inc": incrementVersion},
+		)
+		if err != nil {
+			return err
+		}
+		if result.ModifiedCount == 0 {
+			return errSecondFactorInvalid
+		}
+		recordAudit(ctx, c, models.AuditRecoveryCodeUsed, user.ID, map[string]interface{}{"remaining": len(user.RecoveryCodes) - 1})
+		return nil
+	}
+
+	secret, err := libs.OpenString(mfaEncryptionKey(), user.TOTPSecret)
+	if err != nil {
+		return err
+	}
+	step, ok := libs.ValidateTOTP(secret, request.Code, time.Now(), 1)
+	if !ok {
+		return errSecondFactorInvalid
+	}
+
+	// Record the step; the filter fails for a code that was already used
+	result, err := usersCollection().UpdateOne(ctx,
+		bson.M{"_id": user.ID, "totp_last_step": bson.M{"$not": bson.M{"$gte": step}}},
+		bson.M{"$set": bson.M{"totp_last_step": step}, "$inc": incrementVersion},
+	)
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount == 0 {
+		return errSecondFactorInvalid
+	}
+	return nil
+}
+
+// currentUser loads the user making the request
+func currentUser(ctx context.Context, c *fiber.Ctx) (*models.User, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+	}
+	var user models.User
+	if err := usersCollection().FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+		}
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching user from database"})
+	}
+	return &user, nil
+}
+
+// requireReauthentication checks that the current session may make a change that grants a new way
+// to log in, such as registering a passkey. Users who have MFA enabled or whose role requires it need
+// a session verified with a second factor; everyone else confirms their current password. When it
+// returns false the error response has already been sent.
+func requireReauthentication(c *fiber.Ctx, user models.User, password, action string) (bool, error) {
+	claims, err := currentClaims(c)
+	if err != nil {
+		return false, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+	}
+	if user.MFAEnabledAt != nil || middleware.MFARequired(string(user.Role)) {
+		if !claims.MFA {
+			return false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":        "Log in with your second factor to " + action,
+				"mfa_required": true,
+			})
+		}
+		return true, nil
+	}
+	if user.Password == "" || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return false, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Enter your current password to " + action})
+	}
+	return true, nil
+}
+
+// parseSecondFactor reads a SecondFactorRequest and verifies it for the user. When it returns
+// false the error response has already been sent.
+func parseSecondFactor(ctx context.Context, c *fiber.Ctx, user models.User) (bool, error) {
+	var request SecondFactorRequest
+	if err := c.BodyParser(&request); err != nil {
+		return false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+	}
+	if request.Code == "" && request.RecoveryCode == "" {
+		return false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "code or recovery_code is required"})
+	}
+	if err := verifySecondFactor(ctx, c, user, request); err != nil {
+		return false, secondFactorError(c, err)
+	}
+	return true, nil
+}
+
+// GetMFAStatus shows whether the current user has MFA enabled and whether their role requires it
+func GetMFAStatus(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := currentUser(ctx, c)
+	if user == nil {
+		return err
+	}
+	claims, _ := currentClaims(c)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"enabled":                  user.MFAEnabledAt != nil,
+		"enabled_at":               user.MFAEnabledAt,
+		"required":                 middleware.MFARequired(string(user.Role)),
+		"session_verified":         claims != nil && claims.MFA,
+		"recovery_codes_remaining": len(user.RecoveryCodes),
+	})
+}
+
+// BeginTOTPEnrollment creates a new TOTP secret for the current user. The provisioning URI is
+// shown as a QR code by the client; the secret is activated by ConfirmTOTPEnrollment.
+func BeginTOTPEnrollment(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := currentUser(ctx, c)
+	if user == nil {
+		return err
+	}
+	if user.MFAEnabledAt != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is already enabled, disable it first"})
+	}
+
+	secret, err := libs.GenerateTOTPSecret()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate secret"})
+	}
+	sealed, err := libs.SealString(mfaEncryptionKey(), secret)
+	if err != nil {
+		log.Println("Failed to encrypt TOTP secret:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate secret"})
+	}
+
+	_, err = usersCollection().UpdateOne(ctx,
+		bson.M{"_id": user.ID},
+		bson.M{"$set": bson.M{"totp_pending_secret": sealed}, "$inc": incrementVersion},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating user in database"})
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"secret":           secret,
+		"provisioning_uri": libs.TOTPProvisioningURI(mfaIssuer(), user.Email, secret),
+	})
+}
+
+// ConfirmTOTPEnrollment activates the pending secret with a code from the authenticator app and
+// returns the recovery codes, which are shown only once. The current session is replaced by one
+// that counts as verified with a second factor.
+func ConfirmTOTPEnrollment(c *fiber.Ctx) error {
+	type request struct {
+		Code string `json:"code" validate:"required"`
+	}
+	var req request
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+	}
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": validationErr.Error()})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := currentUser(ctx, c)
+	if user == nil {
+		return err
+	}
+	if user.MFAEnabledAt != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is already enabled"})
+	}
+	if user.TOTPPendingSecret == "" {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Start enrollment at POST /mfa/totp first"})
+	}
+
+	secret, err := libs.OpenString(mfaEncryptionKey(), user.TOTPPendingSecret)
+	if err != nil {
+		log.Println("Failed to decrypt TOTP secret:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify code"})
+	}
+	step, ok := libs.ValidateTOTP(secret, req.Code, time.Now(), 1)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": errSecondFactorInvalid.Error()})
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate recovery codes"})
+	}
+
+	// The pending secret must not have been replaced by another enrollment in the meantime
+	now := time.Now()
+	result, err := usersCollection().UpdateOne(ctx,
+		bson.M{"_id": user.ID, "totp_pending_secret": user.TOTPPendingSecret},
+		bson.M{
+			"$set":   bson.M{"totp_secret": user.TOTPPendingSecret, "totp_last_step": step, "recovery_codes": hashes, "mfa_enabled_at": now, "updated_at": now},
+			"$unset": bson.M{"totp_pending_secret": ""},
+			"$inc":   incrementVersion,
+		},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating user in database"})
+	}
+	if result.MatchedCount == 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Enrollment was restarted, scan the new code"})
+	}
+
+	recordAudit(ctx, c, models.AuditMFAEnabled, user.ID, nil)
+
+	// Replace the session with one that passed the second factor
+	claims, _ := currentClaims(c)
+	if sessionID, err := primitive.ObjectIDFromHex(claims.SessionID); err == nil {
+		if err := endSession(ctx, sessionID); err != nil {
+			log.Println("Failed to end session:", err)
+		}
+	}
+	tokens, err := startSession(ctx, c, *user, false, true)
+	if err != nil {
+		log.Printf("Error issuing session tokens: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error issuing tokens"})
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"recovery_codes": codes,
+		"token":          tokens.AccessToken,
+		"refresh_token":  tokens.RefreshToken,
+		"expires_in":     tokens.ExpiresIn,
+	})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the current user. It requires a code from
+// the authenticator app.
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := currentUser(ctx, c)
+	if user == nil {
+		return err
+	}
+	if user.MFAEnabledAt == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is not enabled"})
+	}
+	if ok, err := parseSecondFactor(ctx, c, *user); !ok {
+		return err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate recovery codes"})
+	}
+	_, err = usersCollection().UpdateOne(ctx,
+		bson.M{"_id": user.ID},
+		bson.M{"$set": bson.M{"recovery_codes": hashes, "updated_at": time.Now()}, "$inc": incrementVersion},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating user in database"})
+	}
+
+	recordAudit(ctx, c, models.AuditRecoveryCodesRegenerated, user.ID, nil)
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"recovery_codes": codes})
+}
+
+// disableMFA removes the TOTP secret and recovery codes of a user
+func disableMFA(ctx context.Context, userID primitive.ObjectID) (bool, error) {
+	result, err := usersCollection().UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{
+			"$unset": bson.M{"totp_secret": "", "totp_pending_secret": "", "totp_last_step": "", "recovery_codes": "", "mfa_enabled_at": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+			"$inc":   incrementVersion,
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// DisableTOTP turns off two-factor authentication for the current user, confirmed with a code.
+// Users whose role requires MFA cannot turn it off.
+func DisableTOTP(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := currentUser(ctx, c)
+	if user == nil {
+		return err
+	}
+	if user.MFAEnabledAt == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is not enabled"})
+	}
+	if middleware.MFARequired(string(user.Role)) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Two-factor authentication is required for your role"})
+	}
+	if ok, err := parseSecondFactor(ctx, c, *user); !ok {
+		return err
+	}
+
+	if _, err := disableMFA(ctx, user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating user in database"})
+	}
+	recordAudit(ctx, c, models.AuditMFADisabled, user.ID, nil)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Two-factor authentication disabled"})
+}
+
+// ResetUserMFA lets an administrator remove the second factor of a user who lost their device.
+// The user is logged out everywhere and enrolls again at their next login.
+func ResetUserMFA(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	found, err := disableMFA(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating user in database"})
+	}
+	if !found {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if err := endAllSessions(ctx, userID); err != nil {
+		log.Println("Failed to end sessions:", err)
+	}
+	clearMFAAttempts(ctx, userID)
+	recordAudit(ctx, c, models.AuditMFAReset, userID, nil)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Two-factor authentication reset"})
+}
@@ -0,0 +1,542 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"myfiberproject/config"
+	"myfiberproject/database"
+	"myfiberproject/libs"
+	"myfiberproject/models"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Single sign-on with an OpenID Connect provider. GET /auth/oidc/login redirects to the provider
+// with PKCE; the provider returns to GET /auth/oidc/callback, which validates the ID token,
+// provisions or updates the user and redirects to the frontend with a one-time login code. The
+// frontend exchanges the code at POST /auth/oidc/token for the same tokens Login returns.
+//
+// Starting a flow also sets a cookie that only the callback receives, and the callback refuses a
+// flow that does not come back to the browser holding it. Otherwise a link to the callback with
+// someone else's code and state would log a victim into that account, or link it to theirs.
+//
+// Accounts created by SSO get their role from their groups at the provider, again on every login:
+// OIDC_GROUP_ROLES maps groups to roles, such as "cms-admins=administrator,press=comms". The first
+// listed group the user belongs to wins. Users in none of the groups get OIDC_DEFAULT_ROLE, or are
+// refused when it is empty.
+//
+// Existing accounts are never linked by email address, which would hand accounts with a password
+// or MFA to whoever controls the address at the provider. Their owners link them from a logged-in
+// session at POST /auth/oidc/link instead, and keep the role and status they have.
+
+const (
+	oidcStateTTL     = 10 * time.Minute
+	oidcLoginCodeTTL = time.Minute
+	oidcFlowCookie   = "oidc_flow" // Binds a flow to the browser that started it
+)
+
+var (
+	oidcProvider *libs.OIDCProvider
+	oidcMu       sync.Mutex
+)
+
+var (
+	errOIDCNotAllowed   = errors.New("your account is not allowed to sign in")
+	errOIDCEmailMissing = errors.New("the identity provider did not share a verified email address")
+	errOIDCLinkConflict = errors.New("this identity is already linked to another account")
+	errOIDCLinkRequired = errors.New("an account with this email address already exists, log in and link it to single sign-on first")
+)
+
+// oidcGroupRole maps a group at the provider to a role
+type oidcGroupRole struct {
+	Group string
+	Role  models.Role
+}
+
+var assignableRoles = map[models.Role]bool{
+	models.Administrator: true,
+	models.Viewer:        true,
+	models.PO:            true,
+	models.IT:            true,
+	models.Comms:         true,
+	models.HR:            true,
+	models.CMAS:          true,
+}
+
+func oidcEnabled() bool {
+	return config.GetEnv("OIDC_ISSUER_URL", "") != ""
+}
+
+// oidcRedirectURL is the address of OIDCCallback registered at the provider
+func oidcRedirectURL() string {
+	return config.GetEnv("OIDC_REDIRECT_URL", "http://localhost:8080/auth/oidc/callback")
+}
+
+// getOIDCProvider discovers the provider on first use. Failures are not cached, so a provider that
+// was down at startup is picked up once it is back.
+func getOIDCProvider(ctx context.Context) (*libs.OIDCProvider, error) {
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+	if oidcProvider != nil {
+		return oidcProvider, nil
+	}
+
+	provider, err := libs.DiscoverOIDC(ctx, libs.OIDCConfig{
+		IssuerURL:    config.GetEnv("OIDC_ISSUER_URL", ""),
+		ClientID:     config.GetEnv("OIDC_CLIENT_ID", ""),
+		ClientSecret: config.GetEnv("OIDC_CLIENT_SECRET", ""),
+		RedirectURL:  oidcRedirectURL(),
+		Scopes:       strings.Fields(config.GetEnv("OIDC_SCOPES", "openid email profile")),
+		GroupsClaim:  config.GetEnv("OIDC_GROUPS_CLAIM", "groups"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	oidcProvider = provider
+	return oidcProvider, nil
+}
+
+// parseOIDCGroupRoles reads OIDC_GROUP_ROLES, skipping entries with an unknown role
+func parseOIDCGroupRoles() []oidcGroupRole {
+	var mappings []oidcGroupRole
+	for _, entry := range strings.Split(config.GetEnv("OIDC_GROUP_ROLES", ""), ",") {
+		group, role, found := strings.Cut(entry, "=")
+		group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+		if !found || group == "" {
+			continue
+		}
+		if !assignableRoles[models.Role(role)] {
+			log.Printf("OIDC_GROUP_ROLES: ignoring unknown role %q for group %q", role, group)
+			continue
+		}
+		mappings = append(mappings, oidcGroupRole{Group: group, Role: models.Role(role)})
+	}
+	return mappings
+}
+
+// oidcRole picks the role for a user's groups
+func oidcRole(groups []string) (models.Role, error) {
+	member := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		member[group] = true
+	}
+	for _, mapping := range parseOIDCGroupRoles() {
+		if member[mapping.Group] {
+			return mapping.Role, nil
+		}
+	}
+	if role := models.Role(config.GetEnv("OIDC_DEFAULT_ROLE", "")); assignableRoles[role] {
+		return role, nil
+	}
+	return "", errOIDCNotAllowed
+}
+
+// oidcMFA reports whether the provider's login counts as multi-factor. Providers that enforce MFA
+// themselves can be trusted with OIDC_TRUST_MFA=true; otherwise the amr claim must say so.
+func oidcMFA(claims *libs.OIDCClaims) bool {
+	trust, err := strconv.ParseBool(config.GetEnv("OIDC_TRUST_MFA", "false"))
+	if err != nil {
+		log.Printf("OIDC_TRUST_MFA: ignoring invalid value: %v", err)
+	}
+	if trust {
+		return true
+	}
+	for _, method := range claims.AMR {
+		if method == "mfa" {
+			return true
+		}
+	}
+	return false
+}
+
+// provisionOIDCUser finds the user behind an ID token, creating them on their first login. Only the
+// role of accounts created here follows the provider's groups; linked accounts are left as they are.
+func provisionOIDCUser(ctx context.Context, c *fiber.Ctx, issuer string, claims *libs.OIDCClaims) (*models.User, error) {
+	collection := usersCollection()
+	var user models.User
+	err := collection.FindOne(ctx, bson.M{"oidc_issuer": issuer, "oidc_subject": claims.Subject}).Decode(&user)
+	if err == nil {
+		if !user.OIDCProvisioned {
+			return &user, nil
+		}
+		return syncOIDCRole(ctx, c, issuer, user, claims.Groups)
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	role, err := oidcRole(claims.Groups)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errOIDCEmailMissing
+	}
+	count, err := collection.CountDocuments(ctx, bson.M{"email": claims.Email})
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errOIDCLinkRequired
+	}
+
+	// The password is random and never shown; SSO users can set one with forgot password
+	password, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	user = models.User{
+		ID:              primitive.NewObjectID(),
+		FullName:        claims.Name,
+		Email:           claims.Email,
+		Password:        string(hashedPassword),
+		Role:            role,
+		Status:          models.Approved,
+		EmailVerifiedAt: &now,
+		OIDCIssuer:      issuer,
+		OIDCSubject:     claims.Subject,
+		OIDCProvisioned: true,
+		Version:         1,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if user.FullName == "" {
+		user.FullName = claims.Email
+	}
+	if _, err := collection.InsertOne(ctx, user); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errOIDCLinkRequired
+		}
+		return nil, err
+	}
+	recordAudit(ctx, c, models.AuditUserProvisioned, user.ID, map[string]interface{}{"email": user.Email, "role": user.Role, "issuer": issuer})
+	return &user, nil
+}
+
+// syncOIDCRole updates the role of an account created by SSO to match the user's groups. Users who
+// left every mapped group are refused.
+func syncOIDCRole(ctx context.Context, c *fiber.Ctx, issuer string, user models.User, groups []string) (*models.User, error) {
+	role, err := oidcRole(groups)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == role {
+		return &user, nil
+	}
+
+	_, err = usersCollection().UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+		"$set": bson.M{"role": role, "updated_at": time.Now()},
+		"$inc": incrementVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+	recordAudit(ctx, c, models.AuditUserRoleSynced, user.ID, map[string]interface{}{"from": user.Role, "to": role, "issuer": issuer})
+	user.Role = role
+	return &user, nil
+}
+
+// linkOIDCIdentity links the identity behind an ID token to an existing account, as requested by
+// the account's owner from a logged-in session
+func linkOIDCIdentity(ctx context.Context, c *fiber.Ctx, userID primitive.ObjectID, issuer string, claims *libs.OIDCClaims) error {
+	result, err := usersCollection().UpdateOne(ctx,
+		bson.M{"_id": userID, "oidc_subject": bson.M{"$exists": false}},
+		bson.M{
+			"$set": bson.M{"oidc_issuer": issuer, "oidc_subject": claims.Subject, "updated_at": time.Now()},
+			"$inc": incrementVersion,
+		},
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return errOIDCLinkConflict
+	}
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errOIDCLinkConflict
+	}
+	recordAudit(ctx, c, models.AuditUserOIDCLinked, userID, map[string]interface{}{"issuer": issuer, "email": claims.Email})
+	return nil
+}
+
+// oidcFrontendRedirect sends the browser back to the frontend's SSO page
+func oidcFrontendRedirect(c *fiber.Ctx, query url.Values) error {
+	return c.Redirect(frontendLink(config.GetEnv("OIDC_FRONTEND_CALLBACK_PATH", "/auth/callback"), query), fiber.StatusFound)
+}
+
+// BeginOIDCLogin redirects to the identity provider. ?rememberMe=true selects the longer session.
+func BeginOIDCLogin(c *fiber.Ctx) error {
+	if !oidcEnabled() {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Single sign-on is not configured"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	provider, err := getOIDCProvider(ctx)
+	if err != nil {
+		log.Println("OIDC provider unavailable:", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "The identity provider is unavailable"})
+	}
+
+	authURL, err := startOIDCFlow(ctx, c, provider, models.OIDCState{RememberMe: c.QueryBool("rememberMe")})
+	if err != nil {
+		log.Println("Failed to start OIDC login:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start login"})
+	}
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// BeginOIDCLink starts linking the current account to the identity provider. It returns the URL to
+// send the browser to; the callback links the account and redirects to the frontend with
+// ?linked=true. Linking adds a way to log in, so it needs the same re-authentication as passkeys.
+// The response sets the flow cookie, so the frontend sends the request with credentials.
+func BeginOIDCLink(c *fiber.Ctx) error {
+	if !oidcEnabled() {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Single sign-on is not configured"})
+	}
+	type request struct {
+		Password string `json:"password"`
+	}
+	var req request
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	user, err := currentUser(ctx, c)
+	if user == nil {
+		return err
+	}
+	if ok, err := requireReauthentication(c, *user, req.Password, "link single sign-on"); !ok {
+		return err
+	}
+	if user.OIDCSubject != "" {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Your account is already linked to single sign-on"})
+	}
+
+	provider, err := getOIDCProvider(ctx)
+	if err != nil {
+		log.Println("OIDC provider unavailable:", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "The identity provider is unavailable"})
+	}
+	authURL, err := startOIDCFlow(ctx, c, provider, models.OIDCState{LinkUserID: &user.ID})
+	if err != nil {
+		log.Println("Failed to start OIDC link:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start linking"})
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"url": authURL})
+}
+
+// startOIDCFlow stores the state of a login or link, binds it to the browser with the flow cookie
+// and returns the provider URL that starts it
+func startOIDCFlow(ctx context.Context, c *fiber.Ctx, provider *libs.OIDCProvider, stored models.OIDCState) (string, error) {
+	state, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	browserSecret, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	verifier, challenge, err := libs.NewPKCEVerifier()
+	if err != nil {
+		return "", err
+	}
+
+	stored.ID = primitive.NewObjectID()
+	stored.StateHash = hashToken(state)
+	stored.BrowserHash = hashToken(browserSecret)
+	stored.Nonce = nonce
+	stored.CodeVerifier = verifier
+	stored.ExpiresAt = time.Now().Add(oidcStateTTL)
+	if _, err := oidcStateCollection().InsertOne(ctx, stored); err != nil {
+		return "", err
+	}
+
+	setOIDCFlowCookie(c, browserSecret, stored.ExpiresAt)
+	return provider.AuthCodeURL(state, nonce, challenge), nil
+}
+
+// setOIDCFlowCookie sets the flow cookie for the callback only. Lax lets it come along on the
+// redirect from the provider. An empty value clears it.
+func setOIDCFlowCookie(c *fiber.Ctx, value string, expires time.Time) {
+	callbackPath := "/auth/oidc/callback"
+	redirectURL, err := url.Parse(oidcRedirectURL())
+	if err == nil && redirectURL.Path != "" {
+		callbackPath = redirectURL.Path
+	}
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    value,
+		Path:     callbackPath,
+		Expires:  expires,
+		Secure:   err == nil && redirectURL.Scheme == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// OIDCCallback completes the login at the provider and redirects to the frontend with a one-time
+// login code, or with an error
+func OIDCCallback(c *fiber.Ctx) error {
+	if !oidcEnabled() {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Single sign-on is not configured"})
+	}
+	if providerError := c.Query("error"); providerError != "" {
+		return oidcFrontendRedirect(c, url.Values{"error": {providerError}})
+	}
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		return oidcFrontendRedirect(c, url.Values{"error": {"invalid_request"}})
+	}
+
+	// The flow has to come back to the browser that started it
+	browserSecret := c.Cookies(oidcFlowCookie)
+	setOIDCFlowCookie(c, "", time.Unix(0, 0))
+	if browserSecret == "" {
+		return oidcFrontendRedirect(c, url.Values{"error": {"invalid_state"}})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	// Each state is answered once, even when it came back to another browser
+	var stored models.OIDCState
+	err := oidcStateCollection().FindOneAndDelete(ctx, bson.M{"state_hash": hashToken(state), "expires_at": bson.M{"$gt": time.Now()}}).Decode(&stored)
+	if err != nil || subtle.ConstantTimeCompare([]byte(stored.BrowserHash), []byte(hashToken(browserSecret))) != 1 {
+		return oidcFrontendRedirect(c, url.Values{"error": {"invalid_state"}})
+	}
+
+	provider, err := getOIDCProvider(ctx)
+	if err != nil {
+		log.Println("OIDC provider unavailable:", err)
+		return oidcFrontendRedirect(c, url.Values{"error": {"provider_unavailable"}})
+	}
+	claims, err := provider.Exchange(ctx, code, stored.CodeVerifier, stored.Nonce)
+	if err != nil {
+		log.Println("OIDC login failed:", err)
+		return oidcFrontendRedirect(c, url.Values{"error": {"login_failed"}})
+	}
+
+	if stored.LinkUserID != nil {
+		if err := linkOIDCIdentity(ctx, c, *stored.LinkUserID, provider.Issuer, claims); err != nil {
+			if errors.Is(err, errOIDCLinkConflict) {
+				return oidcFrontendRedirect(c, url.Values{"error": {"access_denied"}, "error_description": {err.Error()}})
+			}
+			log.Println("Failed to link OIDC identity:", err)
+			return oidcFrontendRedirect(c, url.Values{"error": {"server_error"}})
+		}
+		return oidcFrontendRedirect(c, url.Values{"linked": {"true"}})
+	}
+
+	user, err := provisionOIDCUser(ctx, c, provider.Issuer, claims)
+	if err != nil {
+		if errors.Is(err, errOIDCNotAllowed) || errors.Is(err, errOIDCEmailMissing) || errors.Is(err, errOIDCLinkRequired) {
+			log.Printf("OIDC login of %s refused: %v", claims.Email, err)
+			return oidcFrontendRedirect(c, url.Values{"error": {"access_denied"}, "error_description": {err.Error()}})
+		}
+		log.Println("Failed to provision OIDC user:", err)
+		return oidcFrontendRedirect(c, url.Values{"error": {"server_error"}})
+	}
+
+	loginCode, err := randomToken()
+	if err != nil {
+		return oidcFrontendRedirect(c, url.Values{"error": {"server_error"}})
+	}
+	_, err = oidcLoginCodeCollection().InsertOne(ctx, models.OIDCLoginCode{
+		ID:         primitive.NewObjectID(),
+		CodeHash:   hashToken(loginCode),
+		UserID:     user.ID,
+		RememberMe: stored.RememberMe,
+		MFA:        oidcMFA(claims),
+		ExpiresAt:  time.Now().Add(oidcLoginCodeTTL),
+	})
+	if err != nil {
+		return oidcFrontendRedirect(c, url.Values{"error": {"server_error"}})
+	}
+
+	return oidcFrontendRedirect(c, url.Values{"code": {loginCode}})
+}
+
+// ExchangeOIDCLoginCode trades the one-time code from OIDCCallback for session tokens, or for an MFA
+// challenge that is completed at POST /login/mfa like a password login
+func ExchangeOIDCLoginCode(c *fiber.Ctx) error {
+	type request struct {
+		Code string `json:"code" validate:"required"`
+	}
+	var req request
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+	}
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": validationErr.Error()})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var loginCode models.OIDCLoginCode
+	err := oidcLoginCodeCollection().FindOneAndDelete(ctx, bson.M{"code_hash": hashToken(req.Code), "expires_at": bson.M{"$gt": time.Now()}}).Decode(&loginCode)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired login code"})
+	}
+
+	var user models.User
+	if err := usersCollection().FindOne(ctx, bson.M{"_id": loginCode.UserID}).Decode(&user); err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	// Users who enabled MFA here still need their second factor unless the provider asked for one
+	if user.MFAEnabledAt != nil && !loginCode.MFA {
+		return mfaChallengeResponse(c, user, loginCode.RememberMe)
+	}
+
+	tokens, err := startSession(ctx, c, user, loginCode.RememberMe, loginCode.MFA)
+	if err != nil {
+		log.Printf("Error issuing session tokens: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error issuing tokens"})
+	}
+
+	user.Password = ""
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"user":          user,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+func oidcStateCollection() *mongo.Collection {
+	return database.GetMongoClient().Database(database.GetDatabaseName()).Collection("oidc_states")
+}
+
+func oidcLoginCodeCollection() *mongo.Collection {
+	return database.GetMongoClient().Database(database.GetDatabaseName()).Collection("oidc_login_codes")
+}
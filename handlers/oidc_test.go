@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"myfiberproject/libs"
+	"myfiberproject/models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestOIDCRole(t *testing.T) {
+	t.Setenv("OIDC_GROUP_ROLES", "cms-admins=administrator, press = comms,hr=hr,ghosts=superuser,=viewer,broken")
+
+	tests := []struct {
+		name        string
+		defaultRole string
+		groups      []string
+		want        models.Role
+		wantErr     bool
+	}{
+		{name: "mapped group", groups: []string{"press"}, want: models.Comms},
+		{name: "first listed group wins", groups: []string{"hr", "press", "cms-admins"}, want: models.Administrator},
+		{name: "groups are case sensitive", groups: []string{"CMS-Admins"}, wantErr: true},
+		{name: "unknown role is ignored", groups: []string{"ghosts"}, wantErr: true},
+		{name: "no groups", wantErr: true},
+		{name: "default role", defaultRole: "viewer", groups: []string{"ghosts"}, want: models.Viewer},
+		{name: "mapped group beats default role", defaultRole: "viewer", groups: []string{"hr"}, want: models.HR},
+		{name: "invalid default role", defaultRole: "superuser", groups: []string{"other"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("OIDC_DEFAULT_ROLE", test.defaultRole)
+			role, err := oidcRole(test.groups)
+			if test.wantErr {
+				if err != errOIDCNotAllowed {
+					t.Fatalf("oidcRole(%q) = %q, %v; want errOIDCNotAllowed", test.groups, role, err)
+				}
+				return
+			}
+			if err != nil || role != test.want {
+				t.Fatalf("oidcRole(%q) = %q, %v; want %q", test.groups, role, err, test.want)
+			}
+		})
+	}
+}
+
+func TestOIDCMFA(t *testing.T) {
+	tests := []struct {
+		trust string
+		amr   []string
+		want  bool
+	}{
+		{trust: "false", amr: []string{"pwd"}, want: false},
+		{trust: "false", amr: []string{"pwd", "mfa"}, want: true},
+		{trust: "true", amr: nil, want: true},
+		{trust: "1", amr: nil, want: true},
+		{trust: "yes", amr: nil, want: false}, // Not a boolean, so the provider is not trusted
+	}
+
+	for _, test := range tests {
+		t.Setenv("OIDC_TRUST_MFA", test.trust)
+		if got := oidcMFA(&libs.OIDCClaims{AMR: test.amr}); got != test.want {
+			t.Errorf("oidcMFA with OIDC_TRUST_MFA=%q and amr %q = %v, want %v", test.trust, test.amr, got, test.want)
+		}
+	}
+}
+
+func TestOIDCCallbackRequiresFlowCookie(t *testing.T) {
+	t.Setenv("OIDC_ISSUER_URL", "https://idp.example.com")
+	t.Setenv("FRONTEND_URL", "https://cms.example.com")
+
+	app := fiber.New()
+	app.Get("/auth/oidc/callback", OIDCCallback)
+
+	// A callback link made by someone else, opened in a browser that never started a flow
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?code=their-code&state=their-state", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusFound {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusFound)
+	}
+	location, err := url.Parse(resp.Header.Get(fiber.HeaderLocation))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location.Host != "cms.example.com" || location.Query().Get("error") != "invalid_state" {
+		t.Fatalf("redirected to %s, want the frontend with error=invalid_state", location)
+	}
+	if location.Query().Get("code") != "" {
+		t.Fatalf("redirected with a login code: %s", location)
+	}
+
+	// The flow cookie is cleared either way
+	cleared := false
+	for _, cookie := range resp.Header.Values(fiber.HeaderSetCookie) {
+		if strings.HasPrefix(cookie, oidcFlowCookie+"=;") {
+			cleared = true
+		}
+	}
+	if !cleared {
+		t.Errorf("flow cookie not cleared: %q", resp.Header.Values(fiber.HeaderSetCookie))
+	}
+}
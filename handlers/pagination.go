@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+	// maxPage keeps the skip of (page-1)*limit far from overflowing; pages that deep are empty anyway
+	maxPage = 100000
+)
+
+type pagination struct {
+	Page  int64
+	Limit int64
+}
+
+// parsePagination reads the "page" and "limit" query parameters, falling back to sane defaults
+func parsePagination(c *fiber.Ctx) pagination {
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	if page > maxPage {
+		page = maxPage
+	}
+
+	limit := c.QueryInt("limit", defaultPageLimit)
+	if limit < 1 || limit > maxPageLimit {
+		limit = defaultPageLimit
+	}
+
+	return pagination{Page: int64(page), Limit: int64(limit)}
+}
+
+// findOptions applies the page to a query
+func (p pagination) findOptions() *options.FindOptions {
+	return options.Find().SetSkip((p.Page - 1) * p.Limit).SetLimit(p.Limit)
+}
+
+// response wraps a page of data with the pagination metadata
+func (p pagination) response(data interface{}, total int64) fiber.Map {
+	return fiber.Map{
+		"data":        data,
+		"page":        p.Page,
+		"limit":       p.Limit,
+		"total":       total,
+		"total_pages": (total + p.Limit - 1) / p.Limit,
+	}
+}
@@ -0,0 +1,505 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"myfiberproject/config"
+	"myfiberproject/database"
+	"myfiberproject/libs"
+	"myfiberproject/models"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Passkeys are WebAuthn credentials. Each ceremony has a begin request, which returns the options
+// for navigator.credentials.create() or .get() and a ceremony ID, and a finish request with the
+// ceremony ID and the credential returned by the browser. Logins require user verification, so a
+// passkey login counts as multi-factor.
+
+const (
+	ceremonyRegistration = "registration"
+	ceremonyLogin        = "login"
+	ceremonyTimeout      = 5 * time.Minute
+)
+
+var (
+	webAuthnInstance *webauthn.WebAuthn
+	webAuthnErr      error
+	webAuthnOnce     sync.Once
+)
+
+var errCeremonyInvalid = errors.New("invalid or expired passkey ceremony")
+
+type FinishPasskeyRequest struct {
+	CeremonyID string          `json:"ceremony_id" validate:"required"`
+	Credential json.RawMessage `json:"credential" validate:"required"` // PublicKeyCredential from the browser, as JSON
+}
+
+type BeginPasskeyLoginRequest struct {
+	MFAToken   string `json:"mfa_token"` // Challenge from Login; the passkey then replaces the TOTP code
+	RememberMe bool   `json:"rememberMe"`
+}
+
+// getWebAuthn configures the relying party from WEBAUTHN_RP_ID, WEBAUTHN_RP_NAME and
+// WEBAUTHN_RP_ORIGINS. They default to the host, site name and origin of the frontend.
+func getWebAuthn() (*webauthn.WebAuthn, error) {
+	webAuthnOnce.Do(func() {
+		frontend := config.GetEnv("FRONTEND_URL", "http://localhost:3000")
+		rpID := config.GetEnv("WEBAUTHN_RP_ID", "")
+		if rpID == "" {
+			if parsed, err := url.Parse(frontend); err == nil {
+				rpID = parsed.Hostname()
+			}
+		}
+		var origins []string
+		for _, origin := range strings.Split(config.GetEnv("WEBAUTHN_RP_ORIGINS", strings.TrimRight(frontend, "/")), ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				origins = append(origins, origin)
+			}
+		}
+		timeout := webauthn.TimeoutConfig{Enforce: true, Timeout: ceremonyTimeout, TimeoutUVD: ceremonyTimeout}
+		webAuthnInstance, webAuthnErr = webauthn.New(&webauthn.Config{
+			RPID:          rpID,
+			RPDisplayName: config.GetEnv("WEBAUTHN_RP_NAME", config.GetEnv("PUBLIC_SITE_NAME", "Marno")),
+			RPOrigins:     origins,
+			Timeouts:      webauthn.TimeoutsConfig{Login: timeout, Registration: timeout},
+		})
+	})
+	return webAuthnInstance, webAuthnErr
+}
+
+func passkeyCollection() *mongo.Collection {
+	return database.GetMongoClient().Database(database.GetDatabaseName()).Collection("passkeys")
+}
+
+func webAuthnCeremonyCollection() *mongo.Collection {
+	return database.GetMongoClient().Database(database.GetDatabaseName()).Collection("webauthn_ceremonies")
+}
+
+// passkeyUser presents a user and their passkeys to the webauthn package. The user handle is the
+// user ID.
+type passkeyUser struct {
+	user     models.User
+	passkeys []models.Passkey
+}
+
+func (u *passkeyUser) WebAuthnID() []byte          { return u.user.ID[:] }
+func (u *passkeyUser) WebAuthnName() string        { return u.user.Email }
+func (u *passkeyUser) WebAuthnDisplayName() string { return u.user.FullName }
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.passkeys))
+	for _, passkey := range u.passkeys {
+		credentials = append(credentials, passkey.Credential)
+	}
+	return credentials
+}
+
+// loadPasskeyUser loads a user with their passkeys
+func loadPasskeyUser(ctx context.Context, userID primitive.ObjectID) (*passkeyUser, error) {
+	var user models.User
+	if err := usersCollection().FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		return nil, err
+	}
+	passkeys, err := listPasskeys(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &passkeyUser{user: user, passkeys: passkeys}, nil
+}
+
+func listPasskeys(ctx context.Context, userID primitive.ObjectID) ([]models.Passkey, error) {
+	cursor, err := passkeyCollection().Find(ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	passkeys := []models.Passkey{}
+	if err := cursor.All(ctx, &passkeys); err != nil {
+		return nil, err
+	}
+	return passkeys, nil
+}
+
+// startCeremony stores the session data of a ceremony and returns its ID
+func startCeremony(ctx context.Context, ceremony models.WebAuthnCeremony, session *webauthn.SessionData) (string, error) {
+	ceremony.ID = primitive.NewObjectID()
+	ceremony.Session = *session
+	ceremony.ExpiresAt = time.Now().Add(ceremonyTimeout)
+	if _, err := webAuthnCeremonyCollection().InsertOne(ctx, ceremony); err != nil {
+		return "", err
+	}
+	return ceremony.ID.Hex(), nil
+}
+
+// finishCeremony removes a ceremony so that its challenge can only be answered once
+func finishCeremony(ctx context.Context, ceremonyID, purpose string) (*models.WebAuthnCeremony, error) {
+	id, err := primitive.ObjectIDFromHex(ceremonyID)
+	if err != nil {
+		return nil, errCeremonyInvalid
+	}
+	var ceremony models.WebAuthnCeremony
+	err = webAuthnCeremonyCollection().FindOneAndDelete(ctx, bson.M{"_id": id, "purpose": purpose, "expires_at": bson.M{"$gt": time.Now()}}).Decode(&ceremony)
+	if err == mongo.ErrNoDocuments {
+		return nil, errCeremonyInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ceremony, nil
+}
+
+// parseFinishPasskeyRequest reads and validates the body of a finish request. When it returns nil
+// the error response has already been sent.
+func parseFinishPasskeyRequest(c *fiber.Ctx) (*FinishPasskeyRequest, error) {
+	var request FinishPasskeyRequest
+	if err := c.BodyParser(&request); err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+	}
+	if validationErr := validate.Struct(&request); validationErr != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": validationErr.Error()})
+	}
+	return &request, nil
+}
+
+// webAuthnError describes a failed ceremony; protocol errors carry a safe explanation
+func webAuthnError(c *fiber.Ctx, err error) error {
+	if err == errCeremonyInvalid {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	var protocolErr *protocol.Error
+	if errors.As(err, &protocolErr) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Passkey verification failed", "details": protocolErr.Details})
+	}
+	log.Println("Passkey ceremony failed:", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Passkey verification failed"})
+}
+
+// BeginPasskeyRegistration starts registering a passkey for the current user. A passkey logs in with
+// a second factor, so registering one needs as much: a session verified with a second factor for
+// users who have MFA enabled or whose role requires it, and the current password for everyone else.
+func BeginPasskeyRegistration(c *fiber.Ctx) error {
+	type request struct {
+		Name     string `json:"name" validate:"max=100"`
+		Password string `json:"password"`
+	}
+	var req request
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+	}
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": validationErr.Error()})
+	}
+
+	wa, err := getWebAuthn()
+	if err != nil {
+		log.Println("WebAuthn is misconfigured:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Passkeys are not configured"})
+	}
+
+	userID, err := currentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := loadPasskeyUser(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+	}
+	if ok, err := requireReauthentication(c, user.user, req.Password, "register a passkey"); !ok {
+		return err
+	}
+
+	// Passkeys are discoverable so that they can log in without an email address
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(user.passkeys))
+	for _, passkey := range user.passkeys {
+		exclusions = append(exclusions, passkey.Credential.Descriptor())
+	}
+	creation, session, err := wa.BeginRegistration(user,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementRequired,
+			UserVerification: protocol.VerificationRequired,
+		}),
+	)
+	if err != nil {
+		return webAuthnError(c, err)
+	}
+
+	name := req.Name
+	if name == "" {
+		name = describeDeviceOrDefault(c)
+	}
+	ceremonyID, err := startCeremony(ctx, models.WebAuthnCeremony{Purpose: ceremonyRegistration, UserID: &userID, Name: name}, session)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start registration"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"ceremony_id": ceremonyID, "options": creation})
+}
+
+// FinishPasskeyRegistration verifies the new credential and stores it as a passkey
+func FinishPasskeyRegistration(c *fiber.Ctx) error {
+	request, err := parseFinishPasskeyRequest(c)
+	if request == nil {
+		return err
+	}
+
+	wa, err := getWebAuthn()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Passkeys are not configured"})
+	}
+
+	userID, err := currentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ceremony, err := finishCeremony(ctx, request.CeremonyID, ceremonyRegistration)
+	if err != nil {
+		return webAuthnError(c, err)
+	}
+	if ceremony.UserID == nil || *ceremony.UserID != userID {
+		return webAuthnError(c, errCeremonyInvalid)
+	}
+
+	user, err := loadPasskeyUser(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+	}
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(request.Credential)
+	if err != nil {
+		return webAuthnError(c, err)
+	}
+	credential, err := wa.CreateCredential(user, ceremony.Session, parsed)
+	if err != nil {
+		return webAuthnError(c, err)
+	}
+
+	passkey := models.Passkey{
+		ID:           primitive.NewObjectID(),
+		UserID:       userID,
+		Name:         ceremony.Name,
+		CredentialID: credential.ID,
+		Credential:   *credential,
+		CreatedAt:    time.Now(),
+	}
+	if _, err := passkeyCollection().InsertOne(ctx, passkey); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "This passkey is already registered"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Cannot insert passkey"})
+	}
+
+	recordAudit(ctx, c, models.AuditPasskeyRegistered, userID, map[string]interface{}{"passkey_id": passkey.ID, "name": passkey.Name})
+
+	return c.Status(fiber.StatusCreated).JSON(passkey)
+}
+
+// GetMyPasskeys lists the passkeys of the current user
+func GetMyPasskeys(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	passkeys, err := listPasskeys(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error querying database"})
+	}
+	return c.Status(fiber.StatusOK).JSON(passkeys)
+}
+
+// DeleteMyPasskey removes one of the current user's passkeys
+func DeleteMyPasskey(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+	}
+	passkeyID, err := primitive.ObjectIDFromHex(c.Params("passkeyId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid passkey ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := passkeyCollection().DeleteOne(ctx, bson.M{"_id": passkeyID, "user_id": userID})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error deleting passkey"})
+	}
+	if result.DeletedCount == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Passkey not found"})
+	}
+
+	recordAudit(ctx, c, models.AuditPasskeyDeleted, userID, map[string]interface{}{"passkey_id": passkeyID})
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Passkey deleted"})
+}
+
+// BeginPasskeyLogin starts a passkey login. Without an MFA challenge the browser offers any passkey
+// of the site; with one, only the passkeys of the user who entered their password.
+func BeginPasskeyLogin(c *fiber.Ctx) error {
+	var request BeginPasskeyLoginRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+	}
+
+	wa, err := getWebAuthn()
+	if err != nil {
+		log.Println("WebAuthn is misconfigured:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Passkeys are not configured"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ceremony := models.WebAuthnCeremony{Purpose: ceremonyLogin, RememberMe: request.RememberMe}
+	var assertion *protocol.CredentialAssertion
+	var session *webauthn.SessionData
+
+	if request.MFAToken != "" {
+		claims, err := parseMFAChallenge(request.MFAToken)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		userID, err := primitive.ObjectIDFromHex(claims.Subject)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA challenge"})
+		}
+		user, err := loadPasskeyUser(ctx, userID)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA challenge"})
+		}
+		if len(user.passkeys) == 0 {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "No passkeys are registered for this account"})
+		}
+		assertion, session, err = wa.BeginLogin(user, webauthn.WithUserVerification(protocol.VerificationRequired))
+		if err != nil {
+			return webAuthnError(c, err)
+		}
+		ceremony.UserID = &userID
+		ceremony.RememberMe = claims.RememberMe
+	} else {
+		assertion, session, err = wa.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+		if err != nil {
+			return webAuthnError(c, err)
+		}
+	}
+
+	ceremonyID, err := startCeremony(ctx, ceremony, session)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start login"})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"ceremony_id": ceremonyID, "options": assertion})
+}
+
+// FinishPasskeyLogin verifies the assertion and starts a session, returning the same tokens as Login
+func FinishPasskeyLogin(c *fiber.Ctx) error {
+	request, err := parseFinishPasskeyRequest(c)
+	if request == nil {
+		return err
+	}
+
+	wa, err := getWebAuthn()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Passkeys are not configured"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ceremony, err := finishCeremony(ctx, request.CeremonyID, ceremonyLogin)
+	if err != nil {
+		return webAuthnError(c, err)
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(request.Credential)
+	if err != nil {
+		return webAuthnError(c, err)
+	}
+
+	var user *passkeyUser
+	var credential *webauthn.Credential
+	if ceremony.UserID != nil {
+		if user, err = loadPasskeyUser(ctx, *ceremony.UserID); err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+		}
+		credential, err = wa.ValidateLogin(user, ceremony.Session, parsed)
+	} else {
+		// The user handle returned by the authenticator is the user ID
+		credential, err = wa.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+			if len(userHandle) != len(primitive.ObjectID{}) {
+				return nil, errors.New("unknown user handle")
+			}
+			var userID primitive.ObjectID
+			copy(userID[:], userHandle)
+			user, err = loadPasskeyUser(ctx, userID)
+			return user, err
+		}, ceremony.Session, parsed)
+	}
+	if err != nil {
+		return webAuthnError(c, err)
+	}
+
+	// A signature counter that did not increase points to a cloned authenticator
+	if credential.Authenticator.CloneWarning {
+		log.Printf("Passkey clone warning for user %s", user.user.ID.Hex())
+		recordAudit(ctx, c, models.AuditPasskeyCloneWarning, user.user.ID, map[string]interface{}{"sign_count": credential.Authenticator.SignCount})
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Passkey verification failed"})
+	}
+
+	_, err = passkeyCollection().UpdateOne(ctx,
+		bson.M{"user_id": user.user.ID, "credential_id": credential.ID},
+		bson.M{"$set": bson.M{"credential": *credential, "last_used_at": time.Now()}},
+	)
+	if err != nil {
+		log.Println("Failed to update passkey:", err)
+	}
+
+	tokens, err := startSession(ctx, c, user.user, ceremony.RememberMe, credential.Flags.UserVerified)
+	if err != nil {
+		log.Printf("Error issuing session tokens: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error issuing tokens"})
+	}
+
+	user.user.Password = ""
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"user":          user.user,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+// describeDeviceOrDefault names a passkey after the device it was registered from
+func describeDeviceOrDefault(c *fiber.Ctx) string {
+	if userAgent := c.Get(fiber.HeaderUserAgent); userAgent != "" {
+		return libs.DescribeDevice(userAgent)
+	}
+	return "Passkey"
+}
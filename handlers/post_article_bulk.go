@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"myfiberproject/database"
+	"myfiberproject/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const maxBulkArticles = 500
+
+type BulkArticleResult struct {
+	Index int    `json:"index"`
+	ID    string `json:"id,omitempty"`
+	Title string `json:"title,omitempty"`
+	Slug  string `json:"slug,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// CreateArticleContentBulk creates the articles of a JSON array in one insert. Every item is validated
+// on its own and reported with its index in the array; invalid items do not stop the others. AI
+// enrichment runs in the background once the articles are stored.
+func CreateArticleContentBulk(c *fiber.Ctx) error {
+	var articles []models.ArticleContent
+	if err := c.BodyParser(&articles); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Expected a JSON array of articles"})
+	}
+	if len(articles) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No articles given"})
+	}
+	if len(articles) > maxBulkArticles {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("At most %d articles can be created at once", maxBulkArticles)})
+	}
+
+	ctx := c.Context()
+	contentCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_content")
+
+	results := make([]BulkArticleResult, len(articles))
+	var documents []interface{}
+	var pending []int // Index in articles of every document
+	batchSlugs := map[string]bool{}
+
+	for i := range articles {
+		articleContent := &articles[i]
+		results[i] = BulkArticleResult{Index: i, Title: articleContent.Title}
+
+		if validationErr := validate.Struct(articleContent); validationErr != nil {
+			results[i].Error = validationErr.Error()
+			continue
+		}
+		if err := prepareArticleBody(articleContent); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		if err := prepareNewArticleContent(ctx, articleContent); err != nil {
+			log.Println("Failed to prepare article content:", err)
+			results[i].Error = "failed to prepare article content"
+			continue
+		}
+
+		// uniqueSlug only sees stored articles, so slugs are also checked against the rest of the batch
+		base := articleContent.Slug
+		for n := 2; batchSlugs[articleContent.Slug]; n++ {
+			slug, err := uniqueSlug(ctx, contentCollection, fmt.Sprintf("%s-%d", base, n), articleContent.ID)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate slug"})
+			}
+			articleContent.Slug = slug
+		}
+		batchSlugs[articleContent.Slug] = true
+
+		documents = append(documents, articleContent)
+		pending = append(pending, i)
+	}
+
+	if len(documents) > 0 {
+		// Unordered, so that one failing document does not stop the ones after it
+		_, err := contentCollection.InsertMany(ctx, documents, options.InsertMany().SetOrdered(false))
+		var bulkErr mongo.BulkWriteException
+		switch {
+		case errors.As(err, &bulkErr):
+			for _, writeErr := range bulkErr.WriteErrors {
+				message := "failed to insert article content"
+				if mongo.IsDuplicateKeyError(writeErr) {
+					message = "an article with this slug already exists"
+				}
+				results[pending[writeErr.Index]].Error = message
+			}
+		case err != nil:
+			log.Println("Failed to insert articles:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to insert articles"})
+		}
+	}
+
+	var created []models.ArticleContent
+	var tags [][]string
+	for _, i := range pending {
+		if results[i].Error != "" {
+			continue
+		}
+		results[i].ID = articles[i].ID.Hex()
+		results[i].Slug = articles[i].Slug
+		created = append(created, articles[i])
+		tags = append(tags, articles[i].Tags)
+		syncArticleSitemapEntry(ctx, articles[i])
+	}
+	if err := refreshTagUsage(ctx, tags...); err != nil {
+		log.Println("Failed to refresh tag usage:", err)
+	}
+
+	queueArticleEnrichment(created)
+
+	status := fiber.StatusCreated
+	if len(created) < len(articles) {
+		status = fiber.StatusMultiStatus
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"message": fmt.Sprintf("%d of %d articles created", len(created), len(articles)),
+		"results": results,
+	})
+}
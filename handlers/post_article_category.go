@@ -22,21 +22,35 @@ func CreateArticleCategory(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": validationErr.Error()})
 	}
 
+	// Fetch the database dynamically
+	db := database.GetMongoClient().Database(database.GetDatabaseName())
+	collection := db.Collection("article_category")
+
 	// Populate additional fields
 	ArticleCategory.ID = primitive.NewObjectID()
 	ArticleCategory.CreatedAt = time.Now()
 	ArticleCategory.UpdatedAt = time.Now()
+	ArticleCategory.PreviousSlugs = nil
 
-	// Fetch the database dynamically
-	db := database.GetMongoClient().Database(database.GetDatabaseName())
-	collection := db.Collection("article_category")
+	// Generate a unique slug from the requested slug or the name
+	slugSource := ArticleCategory.Slug
+	if slugSource == "" {
+		slugSource = ArticleCategory.Name
+	}
+	slug, err := uniqueSlug(c.Context(), collection, slugSource, ArticleCategory.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate slug"})
+	}
+	ArticleCategory.Slug = slug
 
 	// Insert the document into the database
-	_, err := collection.InsertOne(c.Context(), ArticleCategory)
+	_, err = collection.InsertOne(c.Context(), ArticleCategory)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to insert data"})
 	}
 
+	syncCategorySitemapEntry(c.Context(), ArticleCategory)
+
 	// Return the created data
 	return c.Status(fiber.StatusCreated).JSON(ArticleCategory)
 }
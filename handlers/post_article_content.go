@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -18,6 +19,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 func CreateArticleContent(c *fiber.Ctx) error {
@@ -31,60 +33,166 @@ func CreateArticleContent(c *fiber.Ctx) error {
 	if validationErr := validate.Struct(&articleContent); validationErr != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": validationErr.Error()})
 	}
+	if err := prepareArticleBody(&articleContent); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
 
-	// Fetch ArticleCategories from the database
-	categoryCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_category")
+	// Enrich, tag and insert the article
+	if err := createArticleContent(c.Context(), &articleContent); err != nil {
+		log.Println("Failed to create article content:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create article content"})
+	}
 
-	var categories []models.ArticleCategory
-	cursor, err := categoryCollection.Find(c.Context(), bson.M{})
-	if err != nil {
-		log.Println("Failed to fetch article categories:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch article categories"})
+	renderArticleContent(&articleContent)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Article content created successfully",
+		"data":    articleContent,
+	})
+}
+
+// createArticleContent runs the creation pipeline for a validated article: AI enrichment, tags,
+// slug and insertion, followed by the tag usage and sitemap updates.
+func createArticleContent(ctx context.Context, articleContent *models.ArticleContent) error {
+	// Use AI to recommend categories and tags, and to generate an image
+	if err := enrichArticleContent(ctx, articleContent); err != nil {
+		return err
 	}
-	if err := cursor.All(c.Context(), &categories); err != nil {
-		log.Println("Failed to parse article categories:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to parse article categories"})
+
+	if err := prepareNewArticleContent(ctx, articleContent); err != nil {
+		return err
 	}
+	articleContent.EnrichmentStatus = models.EnrichmentDone
 
-	// Use AI to recommend categories
-	recommendedCategories, err := recommendArticleCategories(articleContent.Content, categories)
-	if err != nil {
-		log.Println("Failed to generate AI recommendations:", err)
-		recommendedCategories = []string{}
+	return insertArticleContent(ctx, articleContent)
+}
+
+// maxSlugAttempts bounds how often an insert moves to the next slug suffix after losing a race
+// for its slug to a concurrent insert
+const maxSlugAttempts = 5
+
+// insertArticleContent inserts a prepared article and updates the data derived from it
+func insertArticleContent(ctx context.Context, articleContent *models.ArticleContent) error {
+	contentCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_content")
+
+	// uniqueSlug checks before the insert, so another request may take the slug in between
+	base := articleContent.Slug
+	for attempt := 2; ; attempt++ {
+		_, err := contentCollection.InsertOne(ctx, articleContent)
+		if err == nil {
+			break
+		}
+		if !mongo.IsDuplicateKeyError(err) || attempt > maxSlugAttempts {
+			return fmt.Errorf("failed to insert article content: %w", err)
+		}
+		articleContent.Slug, err = uniqueSlug(ctx, contentCollection, fmt.Sprintf("%s-%d", base, attempt), articleContent.ID)
+		if err != nil {
+			return fmt.Errorf("failed to generate article slug: %w", err)
+		}
 	}
 
-	// Generate an image using the content
-	imageURL, err := generateImageFromContent(articleContent.Content)
+	if err := refreshTagUsage(ctx, articleContent.Tags); err != nil {
+		log.Println("Failed to refresh tag usage:", err)
+	}
+	syncArticleSitemapEntry(ctx, *articleContent)
+	return nil
+}
+
+// prepareNewArticleContent fills in the fields of an article that is about to be inserted
+func prepareNewArticleContent(ctx context.Context, articleContent *models.ArticleContent) error {
+	// Attach the free-form tags, creating the ones that do not exist yet
+	tags, err := resolveTags(ctx, articleContent.Tags)
 	if err != nil {
-		log.Println("Failed to generate image:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate image"})
+		return fmt.Errorf("failed to resolve article tags: %w", err)
 	}
+	articleContent.Tags = tags
 
 	// Populate fields
 	articleContent.ID = primitive.NewObjectID()
 	articleContent.CreatedAt = time.Now()
 	articleContent.UpdatedAt = time.Now()
-	articleContent.Image = imageURL
-	articleContent.RecommendedCategories = recommendedCategories
+	articleContent.PreviousSlugs = nil
+	articleContent.Version = 1
+	articleContent.EnrichmentStatus = models.EnrichmentPending // Until queueArticleEnrichment has run
+	if articleContent.ContentFormat == "" {
+		articleContent.ContentFormat = models.PlainFormat
+	}
+
+	// New articles are drafts unless they are published right away
+	if articleContent.Status == "" {
+		articleContent.Status = models.Draft
+	}
+	articleContent.PublishedAt = nil
+	if articleContent.Status == models.Published {
+		publishedAt := articleContent.CreatedAt
+		articleContent.PublishedAt = &publishedAt
+	}
 
-	// Insert the article into the database
+	// Generate a unique slug from the requested slug or the title
 	contentCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_content")
-	_, err = contentCollection.InsertOne(c.Context(), articleContent)
+	slugSource := articleContent.Slug
+	if slugSource == "" {
+		slugSource = articleContent.Title
+	}
+	articleContent.Slug, err = uniqueSlug(ctx, contentCollection, slugSource, articleContent.ID)
 	if err != nil {
-		log.Println("Failed to insert article content into the database:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to insert article content"})
+		return fmt.Errorf("failed to generate article slug: %w", err)
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message": "Article content created successfully",
-		"data":    articleContent,
-	})
+	return nil
+}
+
+// enrichArticleContent fills in the AI-generated fields of an article: recommended categories and tags,
+// and an image unless the article already has one. Failed recommendations are logged and left empty.
+func enrichArticleContent(ctx context.Context, articleContent *models.ArticleContent) error {
+	if err := recommendArticleContent(ctx, articleContent); err != nil {
+		return err
+	}
+	return generateArticleImage(articleContent)
 }
 
-func recommendArticleCategories(content string, categories []models.ArticleCategory) ([]string, error) {
+// recommendArticleContent fills in the recommended categories and tags of an article
+func recommendArticleContent(ctx context.Context, articleContent *models.ArticleContent) error {
+	// Fetch ArticleCategories from the database
+	categoryCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_category")
+
+	var categories []models.ArticleCategory
+	cursor, err := categoryCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("failed to fetch article categories: %w", err)
+	}
+	if err := cursor.All(ctx, &categories); err != nil {
+		return fmt.Errorf("failed to parse article categories: %w", err)
+	}
+
+	// Use AI to recommend categories and tags
+	recommendedCategories, recommendedTags, err := recommendArticleMetadata(articlePlainText(*articleContent), categories)
+	if err != nil {
+		log.Println("Failed to generate AI recommendations:", err)
+		recommendedCategories, recommendedTags = []string{}, []string{}
+	}
+	articleContent.RecommendedCategories = recommendedCategories
+	articleContent.RecommendedTags = recommendedTags
+	return nil
+}
+
+// generateArticleImage generates an image from the content unless the article already has one
+func generateArticleImage(articleContent *models.ArticleContent) error {
+	if articleContent.Image != "" {
+		return nil
+	}
+	imageURL, err := generateImageFromContent(articlePlainText(*articleContent))
+	if err != nil {
+		return fmt.Errorf("failed to generate image: %w", err)
+	}
+	articleContent.Image = imageURL
+	return nil
+}
+
+func recommendArticleMetadata(content string, categories []models.ArticleCategory) ([]string, []string, error) {
 	openaiAPIKey := os.Getenv("OPENAI_API_KEY")
 	if openaiAPIKey == "" {
-		return nil, fmt.Errorf("OpenAI API key not found in environment variables")
+		return nil, nil, fmt.Errorf("OpenAI API key not found in environment variables")
 	}
 
 	// Prepare the list of category names
@@ -95,7 +203,8 @@ func recommendArticleCategories(content string, categories []models.ArticleCateg
 
 	// Construct the prompt
 	prompt := fmt.Sprintf(`
-Based on the article content below, suggest the most relevant categories from the provided list.
+Based on the article content below, suggest the most relevant categories from the provided list,
+and up to 5 short free-form tags describing the topics of the article.
 
 Content:
 %s
@@ -105,6 +214,7 @@ Available Categories (choose relevant ones):
 
 Respond in the following format:
 Categories: [comma-separated relevant category names]
+Tags: [comma-separated tags]
 `, content, strings.Join(categoryNames, ", "))
 
 	log.Printf("Prompt sent to OpenAI:\n%s", prompt)
@@ -112,14 +222,19 @@ Categories: [comma-separated relevant category names]
 	// Call OpenAI API
 	response, err := libs.CallOpenAI(openaiAPIKey, prompt)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if len(response.Choices) == 0 {
+		return nil, nil, fmt.Errorf("no choices returned from OpenAI API")
 	}
 
 	log.Printf("Response from OpenAI: %+v", response)
 
 	// Parse AI recommendations
-	recommendedCategories := extractRecommendedItems(response.Choices[0].Message.Content, "Categories:")
-	return recommendedCategories, nil
+	answer := response.Choices[0].Message.Content
+	recommendedCategories := extractRecommendedItems(answer, "Categories:")
+	recommendedTags := extractRecommendedItems(answer, "Tags:")
+	return recommendedCategories, recommendedTags, nil
 }
 
 func generateImageFromContent(content string) (string, error) {
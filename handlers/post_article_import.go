@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"myfiberproject/libs"
+	"myfiberproject/models"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	maxImportEntrySize   = 20 * 1024 * 1024  // Largest file extracted from a zip archive or .docx file
+	maxImportArchiveSize = 100 * 1024 * 1024 // Total size extracted from one uploaded file
+)
+
+type ArticleImportResult struct {
+	File  string `json:"file"`
+	ID    string `json:"id,omitempty"`
+	Title string `json:"title,omitempty"`
+	Slug  string `json:"slug,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// ImportArticleContent creates draft articles from uploaded .md, .html and .docx files, or zip archives
+// of them. Embedded images are moved to media storage and AI enrichment runs in the background once
+// the articles are stored. The response reports the outcome of each file. Like bulk creation, an
+// upload or archive holds at most maxBulkArticles documents.
+func ImportArticleContent(c *fiber.Ctx) error {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Expected a multipart form"})
+	}
+
+	files := form.File["files"]
+	if len(files) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No files uploaded in the \"files\" field"})
+	}
+	if len(files) > maxBulkArticles {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("At most %d files can be imported at once", maxBulkArticles)})
+	}
+
+	results := []ArticleImportResult{}
+	for _, header := range files {
+		data, err := readMultipartFile(header)
+		if err != nil {
+			results = append(results, ArticleImportResult{File: header.Filename, Error: err.Error()})
+			continue
+		}
+
+		if strings.EqualFold(path.Ext(header.Filename), ".zip") {
+			results = append(results, importArchive(c.Context(), header.Filename, data)...)
+		} else {
+			budget := libs.NewImportBudget(maxImportEntrySize, maxImportArchiveSize)
+			results = append(results, importDocument(c.Context(), header.Filename, data, nil, budget))
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Import finished",
+		"results": results,
+	})
+}
+
+func readMultipartFile(header *multipart.FileHeader) ([]byte, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, fmt.Errorf("cannot open uploaded file: %v", err)
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// importArchive imports every supported document in a zip archive. Images referenced by relative
+// path are looked up in the archive. The archive and the .docx files in it share one extraction budget.
+func importArchive(ctx context.Context, archiveName string, data []byte) []ArticleImportResult {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return []ArticleImportResult{{File: archiveName, Error: "invalid zip archive"}}
+	}
+
+	entries := map[string]*zip.File{}
+	documents := []*zip.File{}
+	for _, file := range archive.File {
+		entries[path.Clean(file.Name)] = file
+		if !file.FileInfo().IsDir() && !strings.HasPrefix(file.Name, "__MACOSX/") && libs.IsImportableFile(file.Name) {
+			documents = append(documents, file)
+		}
+	}
+	if len(documents) == 0 {
+		return []ArticleImportResult{{File: archiveName, Error: "archive contains no .md, .html or .docx files"}}
+	}
+	if len(documents) > maxBulkArticles {
+		return []ArticleImportResult{{File: archiveName, Error: fmt.Sprintf("archive contains more than %d documents", maxBulkArticles)}}
+	}
+
+	// Limit how much is extracted so that a small archive cannot expand into an enormous one
+	budget := libs.NewImportBudget(maxImportEntrySize, maxImportArchiveSize)
+	readEntry := func(file *zip.File) ([]byte, error) {
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return budget.Extract(file.Name, rc)
+	}
+
+	results := []ArticleImportResult{}
+	for _, file := range documents {
+		name := archiveName + "/" + file.Name
+
+		data, err := readEntry(file)
+		if err != nil {
+			results = append(results, ArticleImportResult{File: name, Error: err.Error()})
+			continue
+		}
+
+		dir := path.Dir(file.Name)
+		resolve := func(ref string) ([]byte, bool) {
+			if unescaped, err := url.PathUnescape(ref); err == nil {
+				ref = unescaped
+			}
+			entry, ok := entries[path.Join(dir, ref)]
+			if !ok {
+				return nil, false
+			}
+			data, err := readEntry(entry)
+			return data, err == nil
+		}
+
+		results = append(results, importDocument(ctx, name, data, resolve, budget))
+	}
+	return results
+}
+
+// importDocument extracts one document, stores its images, creates the article and queues its enrichment
+func importDocument(ctx context.Context, name string, data []byte, resolve libs.ResolveFunc, budget *libs.ImportBudget) ArticleImportResult {
+	result := ArticleImportResult{File: name}
+
+	document, err := libs.ImportFile(name, data, resolve, budget)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	articleContent := models.ArticleContent{
+		Title:         document.Title,
+		Excerpt:       document.Excerpt,
+		Content:       document.Content,
+		ContentFormat: models.ContentFormat(document.Format),
+		Tags:          document.Tags,
+		Status:        models.Draft,
+	}
+
+	// Move embedded images to media storage; the first one becomes the article image
+	for _, image := range document.Images {
+		media, err := storeMedia(ctx, image.FileName, image.Data, name)
+		if err != nil {
+			log.Printf("Failed to store image %s from %s: %v", image.FileName, name, err)
+			continue
+		}
+		articleContent.Content = replaceImageRef(articleContent.Content, image.Ref, media.URL)
+		if articleContent.Image == "" {
+			articleContent.Image = media.URL
+		}
+	}
+
+	if validationErr := validate.Struct(&articleContent); validationErr != nil {
+		result.Error = validationErr.Error()
+		return result
+	}
+
+	if err := prepareNewArticleContent(ctx, &articleContent); err != nil {
+		log.Printf("Failed to import %s: %v", name, err)
+		result.Error = "failed to prepare article content"
+		return result
+	}
+	if err := insertArticleContent(ctx, &articleContent); err != nil {
+		log.Printf("Failed to import %s: %v", name, err)
+		result.Error = "failed to create article content"
+		return result
+	}
+	queueArticleEnrichment([]models.ArticleContent{articleContent})
+
+	result.ID = articleContent.ID.Hex()
+	result.Title = articleContent.Title
+	result.Slug = articleContent.Slug
+	return result
+}
+
+// replaceImageRef points Markdown and HTML image references at the stored image
+func replaceImageRef(content, ref, imageURL string) string {
+	replacer := strings.NewReplacer(
+		"("+ref+")", "("+imageURL+")",
+		"("+ref+" ", "("+imageURL+" ",
+		"<"+ref+">", "<"+imageURL+">",
+		`"`+ref+`"`, `"`+imageURL+`"`,
+		"'"+ref+"'", "'"+imageURL+"'",
+	)
+	return replacer.Replace(content)
+}
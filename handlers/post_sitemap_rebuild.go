@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RebuildSitemap recreates all sitemap entries, e.g. after the initial deployment or a bulk import
+func RebuildSitemap(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	count, err := rebuildSitemapEntries(ctx)
+	if err != nil {
+		log.Println("Failed to rebuild sitemap:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to rebuild sitemap"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Sitemap rebuilt successfully", "entries": count})
+}
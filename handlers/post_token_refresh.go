@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"myfiberproject/database"
+	"myfiberproject/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// RefreshToken exchanges a refresh token for a new access token and the next refresh token.
+// The user is read again, so role and status changes take effect on the next refresh.
+func RefreshToken(c *fiber.Ctx) error {
+	var request RefreshTokenRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse request"})
+	}
+	if validationErr := validate.Struct(&request); validationErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": validationErr.Error()})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stored, err := rotateRefreshToken(ctx, request.RefreshToken)
+	switch err {
+	case nil:
+	case errRefreshTokenInvalid, errRefreshTokenReused:
+		if err == errRefreshTokenReused {
+			log.Printf("Refresh token reuse detected for user %s, session %s ended", stored.UserID.Hex(), stored.FamilyID.Hex())
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	default:
+		log.Println("Failed to rotate refresh token:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to refresh token"})
+	}
+
+	userCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("users")
+	var user models.User
+	err = userCollection.FindOne(ctx, bson.M{"_id": stored.UserID}).Decode(&user)
+	if err != nil && err != mongo.ErrNoDocuments {
+		log.Println("Failed to read user:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to refresh token"})
+	}
+
+	// Deleted users and users who are no longer approved lose the session
+	if err == mongo.ErrNoDocuments || user.Status != models.Approved {
+		if err := endSession(ctx, stored.FamilyID); err != nil {
+			log.Println("Failed to end session:", err)
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found or not approved"})
+	}
+
+	// Sessions started before session details were recorded begin at their oldest remaining token
+	if stored.SessionStartedAt.IsZero() {
+		stored.SessionStartedAt = stored.CreatedAt
+	}
+	setSessionClient(c, &stored)
+	tokens, err := issueSessionTokens(ctx, user, stored)
+	if err != nil {
+		log.Println("Failed to issue session tokens:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to issue tokens"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(tokens)
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"myfiberproject/database"
+	"myfiberproject/libs"
+	"myfiberproject/models"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type WordPressImportItem struct {
+	PostID   int      `json:"post_id"`
+	Title    string   `json:"title,omitempty"`
+	ID       string   `json:"id,omitempty"`
+	Slug     string   `json:"slug,omitempty"`
+	Reason   string   `json:"reason,omitempty"`   // Why the post was skipped
+	Warnings []string `json:"warnings,omitempty"` // Problems that did not stop the import, such as a missing image
+}
+
+type WordPressImportReport struct {
+	CategoriesCreated int                   `json:"categories_created"`
+	Imported          []WordPressImportItem `json:"imported"`
+	Skipped           []WordPressImportItem `json:"skipped"`
+}
+
+// wordPressStatuses maps the WordPress post statuses that are imported to article statuses
+var wordPressStatuses = map[string]models.ArticleStatus{
+	"publish": models.Published,
+	"future":  models.Draft,
+	"draft":   models.Draft,
+	"pending": models.Draft,
+	"private": models.Draft,
+}
+
+// ImportWordPressContent imports the posts of a WordPress export (WXR) uploaded in the "file" field.
+// Categories and tags are created as needed, original slugs and dates are kept and featured images
+// are downloaded into media storage. Pages, trashed posts and posts whose slug is already taken are
+// skipped, so that importing the same file twice does not duplicate articles.
+func ImportWordPressContent(c *fiber.Ctx) error {
+	header, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No WXR file uploaded in the \"file\" field"})
+	}
+	data, err := readMultipartFile(header)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	export, err := libs.ParseWXR(data)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	report, err := importWordPressExport(c.Context(), export)
+	if err != nil {
+		log.Println("Failed to import WordPress export:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to import WordPress export"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Import finished",
+		"data":    report,
+	})
+}
+
+func importWordPressExport(ctx context.Context, export *libs.WXRExport) (WordPressImportReport, error) {
+	report := WordPressImportReport{Imported: []WordPressImportItem{}, Skipped: []WordPressImportItem{}}
+
+	// Map WordPress category slugs to article categories, creating the missing ones
+	categoryIDs := map[string]primitive.ObjectID{}
+	categoryID := func(term libs.WXRTerm) (primitive.ObjectID, error) {
+		if id, ok := categoryIDs[term.Slug]; ok {
+			return id, nil
+		}
+		id, created, err := ensureArticleCategory(ctx, term)
+		if err != nil {
+			return primitive.NilObjectID, err
+		}
+		if created {
+			report.CategoriesCreated++
+		}
+		categoryIDs[term.Slug] = id
+		return id, nil
+	}
+	for _, term := range export.Categories {
+		if _, err := categoryID(term); err != nil {
+			return report, err
+		}
+	}
+
+	attachments := map[int]libs.WXRPost{}
+	for _, post := range export.Posts {
+		if post.Type == "attachment" {
+			attachments[post.ID] = post
+		}
+	}
+
+	contentCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_content")
+	for _, post := range export.Posts {
+		if post.Type == "attachment" {
+			continue
+		}
+		item := WordPressImportItem{PostID: post.ID, Title: post.Title}
+		skip := func(reason string) {
+			item.Reason = reason
+			report.Skipped = append(report.Skipped, item)
+		}
+
+		if post.Type != "post" {
+			skip(fmt.Sprintf("post type %q is not imported", post.Type))
+			continue
+		}
+		status, ok := wordPressStatuses[post.Status]
+		if !ok {
+			skip(fmt.Sprintf("post status %q is not imported", post.Status))
+			continue
+		}
+
+		if slug := libs.Slugify(post.Slug); slug != "" {
+			count, err := contentCollection.CountDocuments(ctx, bson.M{"$or": bson.A{
+				bson.M{"slug": slug},
+				bson.M{"previous_slugs": slug},
+			}})
+			if err != nil {
+				return report, err
+			}
+			if count > 0 {
+				skip(fmt.Sprintf("an article with slug %q already exists", slug))
+				continue
+			}
+		}
+
+		articleContent := models.ArticleContent{
+			Title:         post.Title,
+			Slug:          post.Slug,
+			Excerpt:       post.Excerpt,
+			Content:       post.Content,
+			ContentFormat: models.HTMLFormat,
+			Status:        status,
+		}
+		for _, term := range post.Categories {
+			id, err := categoryID(term)
+			if err != nil {
+				return report, err
+			}
+			articleContent.ArticleCategories = append(articleContent.ArticleCategories, id)
+		}
+		for _, term := range post.Tags {
+			articleContent.Tags = append(articleContent.Tags, term.Name)
+		}
+
+		if validationErr := validate.Struct(&articleContent); validationErr != nil {
+			skip(validationErr.Error())
+			continue
+		}
+
+		// Download the featured image so that enrichment does not generate one
+		if thumbnailID, err := strconv.Atoi(post.Meta["_thumbnail_id"]); err == nil {
+			imageURL, err := importWordPressImage(ctx, attachments[thumbnailID])
+			if err != nil {
+				item.Warnings = append(item.Warnings, "featured image not imported: "+err.Error())
+			} else {
+				articleContent.Image = imageURL
+			}
+		}
+
+		if err := createWordPressArticle(ctx, &articleContent, post); err != nil {
+			log.Printf("Failed to import WordPress post %d: %v", post.ID, err)
+			skip("failed to create article content")
+			continue
+		}
+
+		item.ID = articleContent.ID.Hex()
+		item.Slug = articleContent.Slug
+		report.Imported = append(report.Imported, item)
+	}
+
+	return report, nil
+}
+
+// createWordPressArticle runs the creation pipeline, keeping the dates of the original post.
+// AI enrichment is queued once the article is stored.
+func createWordPressArticle(ctx context.Context, articleContent *models.ArticleContent, post libs.WXRPost) error {
+	if err := prepareNewArticleContent(ctx, articleContent); err != nil {
+		return err
+	}
+
+	if !post.Date.IsZero() {
+		articleContent.CreatedAt = post.Date
+		articleContent.UpdatedAt = post.Modified
+		if articleContent.PublishedAt != nil {
+			publishedAt := post.Date
+			articleContent.PublishedAt = &publishedAt
+		}
+	}
+
+	if err := insertArticleContent(ctx, articleContent); err != nil {
+		return err
+	}
+	queueArticleEnrichment([]models.ArticleContent{*articleContent})
+	return nil
+}
+
+// importWordPressImage copies an attachment into media storage and returns its URL
+func importWordPressImage(ctx context.Context, attachment libs.WXRPost) (string, error) {
+	if attachment.AttachmentURL == "" {
+		return "", fmt.Errorf("attachment not found in the export")
+	}
+
+	data, err := downloadMedia(attachment.AttachmentURL)
+	if err != nil {
+		return "", err
+	}
+	fileName := path.Base(strings.SplitN(attachment.AttachmentURL, "?", 2)[0])
+	media, err := storeMedia(ctx, fileName, data, attachment.AttachmentURL)
+	if err != nil {
+		return "", err
+	}
+	return media.URL, nil
+}
+
+// ensureArticleCategory returns the category with the term's slug, creating it when there is none
+func ensureArticleCategory(ctx context.Context, term libs.WXRTerm) (primitive.ObjectID, bool, error) {
+	collection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_category")
+
+	name := term.Name
+	if name == "" {
+		name = term.Slug
+	}
+	slug := libs.Slugify(term.Slug)
+	if slug == "" {
+		slug = libs.Slugify(name)
+	}
+
+	var existing models.ArticleCategory
+	err := collection.FindOne(ctx, bson.M{"$or": bson.A{
+		bson.M{"slug": slug},
+		bson.M{"previous_slugs": slug},
+	}}).Decode(&existing)
+	if err == nil {
+		return existing.ID, false, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return primitive.NilObjectID, false, err
+	}
+
+	category := models.ArticleCategory{
+		ID:        primitive.NewObjectID(),
+		Name:      name,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	category.Slug, err = uniqueSlug(ctx, collection, slug, category.ID)
+	if err != nil {
+		return primitive.NilObjectID, false, err
+	}
+	if _, err := collection.InsertOne(ctx, category); err != nil {
+		return primitive.NilObjectID, false, err
+	}
+
+	syncCategorySitemapEntry(ctx, category)
+	return category.ID, true, nil
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"myfiberproject/config"
+	"myfiberproject/database"
+	"myfiberproject/models"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// publicSiteURL returns the base URL of the public website that article links point to
+func publicSiteURL() string {
+	return strings.TrimRight(config.GetEnv("PUBLIC_SITE_URL", "http://localhost:3000"), "/")
+}
+
+// publishedArticleFilter restricts article queries to content visible on the public site
+func publishedArticleFilter() bson.M {
+	return bson.M{"status": models.Published}
+}
+
+func toPublicCategory(category models.ArticleCategory) models.PublicCategory {
+	return models.PublicCategory{ID: category.ID, Name: category.Name, Slug: category.Slug}
+}
+
+// toPublicArticles converts articles to their public representation, resolving category references
+func toPublicArticles(ctx context.Context, articles []models.ArticleContent) ([]models.PublicArticle, error) {
+	categoryIDs := []primitive.ObjectID{}
+	for _, article := range articles {
+		categoryIDs = append(categoryIDs, article.ArticleCategories...)
+	}
+
+	categoryCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_category")
+	cursor, err := categoryCollection.Find(ctx, bson.M{"_id": bson.M{"$in": categoryIDs}})
+	if err != nil {
+		return nil, err
+	}
+	var categories []models.ArticleCategory
+	if err := cursor.All(ctx, &categories); err != nil {
+		return nil, err
+	}
+
+	categoriesByID := map[primitive.ObjectID]models.PublicCategory{}
+	for _, category := range categories {
+		categoriesByID[category.ID] = toPublicCategory(category)
+	}
+
+	renderArticleContents(articles)
+
+	publicArticles := []models.PublicArticle{}
+	for _, article := range articles {
+		publicArticle := models.PublicArticle{
+			ID:          article.ID,
+			Title:       article.Title,
+			Slug:        article.Slug,
+			Excerpt:     article.Excerpt,
+			ContentHTML: article.ContentHTML,
+			Blocks:      article.Blocks,
+			Image:       article.Image,
+			Categories:  []models.PublicCategory{},
+			Tags:        article.Tags,
+			PublishedAt: article.PublishedAt,
+			UpdatedAt:   article.UpdatedAt,
+		}
+		if publicArticle.Tags == nil {
+			publicArticle.Tags = []string{}
+		}
+		for _, id := range article.ArticleCategories {
+			if category, ok := categoriesByID[id]; ok {
+				publicArticle.Categories = append(publicArticle.Categories, category)
+			}
+		}
+		publicArticles = append(publicArticles, publicArticle)
+	}
+
+	return publicArticles, nil
+}
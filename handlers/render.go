@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"log"
+	"myfiberproject/libs"
+	"myfiberproject/models"
+)
+
+// renderArticleContent fills in the sanitized HTML form of the article body, from its blocks if it has any.
+// Articles created before content formats existed are treated as plain text.
+func renderArticleContent(article *models.ArticleContent) {
+	if article.ContentFormat == "" {
+		article.ContentFormat = models.PlainFormat
+	}
+	if len(article.Blocks) > 0 {
+		article.ContentHTML = renderBlocksHTML(article.Blocks)
+		return
+	}
+
+	contentHTML, err := libs.RenderContent(string(article.ContentFormat), article.Content)
+	if err != nil {
+		log.Printf("Failed to render content of article %s: %v", article.ID.Hex(), err)
+		return
+	}
+	article.ContentHTML = contentHTML
+}
+
+func renderArticleContents(articles []models.ArticleContent) {
+	for i := range articles {
+		renderArticleContent(&articles[i])
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"myfiberproject/database"
+	"myfiberproject/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+// ResetPassword sets a new password with a token sent by ForgotPassword. The token is consumed,
+// and the user is logged out of every session.
+func ResetPassword(c *fiber.Ctx) error {
+	var request ResetPasswordRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+	}
+	if validationErr := validate.Struct(&request); validationErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": validationErr.Error()})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Consume the token; a used or expired token does not match
+	var reset models.PasswordReset
+	err := passwordResetCollection().FindOneAndUpdate(
+		ctx,
+		bson.M{"token_hash": hashToken(request.Token), "used_at": bson.M{"$exists": false}, "expires_at": bson.M{"$gt": time.Now()}},
+		bson.M{"$set": bson.M{"used_at": time.Now()}},
+	).Decode(&reset)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired reset token"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+
+	hashedPassword, err := hashPassword(request.Password)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to hash password"})
+	}
+
+	userCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("users")
+	result, err := userCollection.UpdateOne(ctx, bson.M{"_id": reset.UserID}, bson.M{
+		"$set": bson.M{"password": hashedPassword, "updated_at": time.Now()},
+		"$inc": incrementVersion,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update password"})
+	}
+	if result.MatchedCount == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired reset token"})
+	}
+
+	// Whoever knew the old password is logged out
+	if err := endAllSessions(ctx, reset.UserID); err != nil {
+		log.Println("Failed to end sessions after password reset:", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Password has been reset, you can now log in"})
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"myfiberproject/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// A session is a refresh token family. Only the latest token of an active family is unused and
+// unrevoked, so listing those tokens lists the sessions.
+
+// listSessions returns the active sessions of a user, most recently used first
+func listSessions(ctx context.Context, userID primitive.ObjectID, currentSessionID string) ([]models.Session, error) {
+	cursor, err := refreshTokenCollection().Find(ctx, bson.M{
+		"user_id":    userID,
+		"used_at":    bson.M{"$exists": false},
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now()},
+	}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	var tokens []models.RefreshToken
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+
+	sessions := []models.Session{}
+	for _, token := range tokens {
+		createdAt := token.SessionStartedAt
+		if createdAt.IsZero() {
+			createdAt = token.CreatedAt
+		}
+		sessions = append(sessions, models.Session{
+			ID:         token.FamilyID,
+			UserID:     token.UserID,
+			Device:     token.Device,
+			IP:         token.IP,
+			UserAgent:  token.UserAgent,
+			RememberMe: token.RememberMe,
+			MFA:        token.MFA,
+			Current:    token.FamilyID.Hex() == currentSessionID,
+			LastSeenAt: token.CreatedAt,
+			CreatedAt:  createdAt,
+			ExpiresAt:  token.ExpiresAt,
+		})
+	}
+	return sessions, nil
+}
+
+// revokeSession ends one session of a user. It reports false when the user has no such session.
+func revokeSession(ctx context.Context, userID, sessionID primitive.ObjectID) (bool, error) {
+	count, err := refreshTokenCollection().CountDocuments(ctx, bson.M{"user_id": userID, "family_id": sessionID})
+	if err != nil || count == 0 {
+		return false, err
+	}
+	return true, endSession(ctx, sessionID)
+}
+
+// GetMySessions lists the active sessions of the current user
+func GetMySessions(c *fiber.Ctx) error {
+	claims, err := currentClaims(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+	}
+	return sendSessions(c, userID, claims.SessionID)
+}
+
+// RevokeMySession ends one of the current user's sessions, for example a lost device
+func RevokeMySession(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+	}
+	return revokeSessionOf(c, userID, c.Params("sessionId"))
+}
+
+// GetUserSessions lists the active sessions of any user, for administrators
+func GetUserSessions(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	currentSessionID := ""
+	if claims, err := currentClaims(c); err == nil {
+		currentSessionID = claims.SessionID
+	}
+	return sendSessions(c, userID, currentSessionID)
+}
+
+// RevokeUserSession ends one session of any user, for administrators
+func RevokeUserSession(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	return revokeSessionOf(c, userID, c.Params("sessionId"))
+}
+
+// RevokeAllUserSessions ends every session of any user, for administrators
+func RevokeAllUserSessions(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := endAllSessions(ctx, userID); err != nil {
+		log.Println("Failed to end sessions:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke sessions"})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "All sessions revoked"})
+}
+
+func sendSessions(c *fiber.Ctx, userID primitive.ObjectID, currentSessionID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sessions, err := listSessions(ctx, userID, currentSessionID)
+	if err != nil {
+		log.Println("Failed to list sessions:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list sessions"})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": sessions})
+}
+
+func revokeSessionOf(c *fiber.Ctx, userID primitive.ObjectID, sessionParam string) error {
+	sessionID, err := primitive.ObjectIDFromHex(sessionParam)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid session ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	found, err := revokeSession(ctx, userID, sessionID)
+	if err != nil {
+		log.Println("Failed to revoke session:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke session"})
+	}
+	if !found {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Session not found"})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Session revoked"})
+}
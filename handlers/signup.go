@@ -5,12 +5,10 @@ import (
 	"log"
 	"myfiberproject/database"
 	"myfiberproject/models"
-	"os"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v4"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -63,6 +61,10 @@ func Signup(c *fiber.Ctx) error {
 
 	// Populate additional fields
 	user.ID = primitive.NewObjectID()
+	user.Version = 1
+	user.EmailVerifiedAt = nil // Set by VerifyEmail
+	user.MFAEnabledAt = nil    // Set by ConfirmTOTPEnrollment
+	user.OIDCIssuer = ""       // Set when the user signs in with SSO
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
 
@@ -74,27 +76,24 @@ func Signup(c *fiber.Ctx) error {
 	}
 	log.Printf("User %s successfully created with ID: %s\n", user.Email, user.ID.Hex())
 
-	// Create JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"id":     user.ID.Hex(),
-		"role":   user.Role,
-		"status": user.Status,
-		"exp":    time.Now().Add(time.Hour * 2).Unix(), // Token expires in 2 hours
-	})
-
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		log.Println("JWT_SECRET is not set in environment variables")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "JWT_SECRET environment variable not set"})
+	// Ask the user to confirm the address; approval waits for it
+	if err := sendVerificationEmail(ctx, user); err != nil {
+		log.Printf("Error sending verification email to %s: %v\n", user.Email, err)
 	}
 
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	// Start a session, as Login does
+	tokens, err := startSession(ctx, c, user, false, false)
 	if err != nil {
-		log.Printf("Error signing token: %v\n", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error signing token"})
+		log.Printf("Error issuing session tokens: %v\n", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error issuing tokens"})
 	}
 
-	// Omit the password in the response and send the token back to the client
+	// Omit the password in the response and send the tokens back to the client
 	user.Password = ""
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"user": user, "token": tokenString})
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"user":          user,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
 }
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"myfiberproject/config"
+	"myfiberproject/database"
+	"myfiberproject/libs"
+	"myfiberproject/models"
+
+	"github.com/patrickmn/go-cache"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Rendered sitemap documents are cached under keys that include a generation number. Invalidating
+// the cache bumps the generation, and documents of older generations expire on their own.
+const sitemapGenerationKey = "sitemap:generation"
+
+var errSitemapPageNotFound = errors.New("sitemap page not found")
+
+func sitemapCollection() *mongo.Collection {
+	return database.GetMongoClient().Database(database.GetDatabaseName()).Collection("sitemap_entries")
+}
+
+func articleSitemapEntry(article models.ArticleContent) models.SitemapEntry {
+	return models.SitemapEntry{
+		ID:      article.ID,
+		Kind:    models.SitemapArticle,
+		Loc:     publicSiteURL() + "/articles/" + article.Slug,
+		LastMod: article.UpdatedAt,
+	}
+}
+
+func categorySitemapEntry(category models.ArticleCategory) models.SitemapEntry {
+	return models.SitemapEntry{
+		ID:      category.ID,
+		Kind:    models.SitemapCategory,
+		Loc:     publicSiteURL() + "/categories/" + category.Slug,
+		LastMod: category.UpdatedAt,
+	}
+}
+
+// syncArticleSitemapEntry adds, updates or removes the sitemap entry of an article depending on whether it is published.
+// Failures are logged only, as the sitemap can always be rebuilt.
+func syncArticleSitemapEntry(ctx context.Context, article models.ArticleContent) {
+	if article.Status != models.Published {
+		removeSitemapEntry(ctx, article.ID)
+		return
+	}
+	upsertSitemapEntry(ctx, articleSitemapEntry(article))
+}
+
+// syncCategorySitemapEntry adds or updates the sitemap entry of a category
+func syncCategorySitemapEntry(ctx context.Context, category models.ArticleCategory) {
+	upsertSitemapEntry(ctx, categorySitemapEntry(category))
+}
+
+func upsertSitemapEntry(ctx context.Context, entry models.SitemapEntry) {
+	_, err := sitemapCollection().ReplaceOne(ctx, bson.M{"_id": entry.ID}, entry, options.Replace().SetUpsert(true))
+	if err != nil {
+		log.Println("Failed to update sitemap entry:", err)
+		return
+	}
+	invalidateSitemapCache()
+}
+
+func removeSitemapEntry(ctx context.Context, id primitive.ObjectID) {
+	if _, err := sitemapCollection().DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		log.Println("Failed to remove sitemap entry:", err)
+		return
+	}
+	invalidateSitemapCache()
+}
+
+// sitemapCacheKey returns the cache key of a rendered sitemap document in the current generation
+func sitemapCacheKey(name string) string {
+	generation, _ := config.CacheInstance.Get(sitemapGenerationKey)
+	return fmt.Sprintf("sitemap:%v:%s", generation, name)
+}
+
+// invalidateSitemapCache makes the rendered sitemap documents be rendered again on the next request
+func invalidateSitemapCache() {
+	for {
+		if _, err := config.CacheInstance.IncrementInt64(sitemapGenerationKey, 1); err == nil {
+			return
+		}
+		// The first invalidation creates the counter; Add fails if another one got there first
+		if config.CacheInstance.Add(sitemapGenerationKey, int64(1), cache.NoExpiration) == nil {
+			return
+		}
+	}
+}
+
+// rebuildSitemapEntries recreates every sitemap entry from the published articles and the categories
+func rebuildSitemapEntries(ctx context.Context) (int, error) {
+	db := database.GetMongoClient().Database(database.GetDatabaseName())
+	entries := []interface{}{}
+
+	cursor, err := db.Collection("article_content").Find(ctx, publishedArticleFilter())
+	if err != nil {
+		return 0, err
+	}
+	for cursor.Next(ctx) {
+		var article models.ArticleContent
+		if err := cursor.Decode(&article); err != nil {
+			return 0, err
+		}
+		entries = append(entries, articleSitemapEntry(article))
+	}
+	if err := cursor.Err(); err != nil {
+		return 0, err
+	}
+
+	cursor, err = db.Collection("article_category").Find(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	for cursor.Next(ctx) {
+		var category models.ArticleCategory
+		if err := cursor.Decode(&category); err != nil {
+			return 0, err
+		}
+		entries = append(entries, categorySitemapEntry(category))
+	}
+	if err := cursor.Err(); err != nil {
+		return 0, err
+	}
+
+	err = database.WithTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		if _, err := sitemapCollection().DeleteMany(sessCtx, bson.M{}); err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		_, err := sitemapCollection().InsertMany(sessCtx, entries)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	invalidateSitemapCache()
+	return len(entries), nil
+}
+
+// renderSitemapPage renders the given 1-based page of sitemap entries as a <urlset> document.
+// Pages past the last one are errSitemapPageNotFound; the first page is always rendered, even when empty.
+func renderSitemapPage(ctx context.Context, page int64) ([]byte, error) {
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetSkip((page - 1) * libs.MaxSitemapURLs).
+		SetLimit(libs.MaxSitemapURLs)
+	cursor, err := sitemapCollection().Find(ctx, bson.M{}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []models.SitemapEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 && page > 1 {
+		return nil, errSitemapPageNotFound
+	}
+
+	urls := make([]libs.SitemapURL, 0, len(entries))
+	for _, entry := range entries {
+		urls = append(urls, libs.SitemapURL{Loc: entry.Loc, LastMod: entry.LastMod})
+	}
+	return libs.RenderSitemap(urls)
+}
+
+// renderSitemapRoot renders /sitemap.xml, which turns into a sitemap index once there are too many URLs for one sitemap.
+// The index points at the public site, like the URLs in the sitemaps themselves.
+func renderSitemapRoot(ctx context.Context) ([]byte, error) {
+	count, err := sitemapCollection().CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	if count <= libs.MaxSitemapURLs {
+		return renderSitemapPage(ctx, 1)
+	}
+
+	pages := (count + libs.MaxSitemapURLs - 1) / libs.MaxSitemapURLs
+	sitemaps := make([]libs.SitemapURL, 0, pages)
+	for page := int64(1); page <= pages; page++ {
+		sitemaps = append(sitemaps, libs.SitemapURL{Loc: fmt.Sprintf("%s/sitemaps/sitemap-%d.xml", publicSiteURL(), page)})
+	}
+	return libs.RenderSitemapIndex(sitemaps)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"myfiberproject/libs"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// uniqueSlug derives a slug from text that no other document in the collection uses,
+// either as its current slug or as one of its previous slugs. Slugs that look like an
+// ObjectID are never returned, as findBySlugOrID would read them as an ID.
+func uniqueSlug(ctx context.Context, collection *mongo.Collection, text string, excludeID primitive.ObjectID) (string, error) {
+	base := libs.Slugify(text)
+	if base == "" {
+		base = "untitled"
+	}
+
+	candidate := base
+	for i := 2; ; i++ {
+		count, err := collection.CountDocuments(ctx, bson.M{
+			"_id": bson.M{"$ne": excludeID},
+			"$or": bson.A{bson.M{"slug": candidate}, bson.M{"previous_slugs": candidate}},
+		})
+		if err != nil {
+			return "", err
+		}
+		if count == 0 && !primitive.IsValidObjectID(candidate) {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// nextPreviousSlugs records oldSlug in the slug history and drops newSlug from it,
+// so that a document can move back to one of its earlier slugs.
+func nextPreviousSlugs(previous []string, oldSlug, newSlug string) []string {
+	slugs := []string{}
+	for _, slug := range previous {
+		if slug != newSlug && slug != oldSlug {
+			slugs = append(slugs, slug)
+		}
+	}
+	if oldSlug != "" && oldSlug != newSlug {
+		slugs = append(slugs, oldSlug)
+	}
+	return slugs
+}
+
+// findBySlugOrID decodes the document addressed by param, which may be an ObjectID, a current slug
+// or a previous slug. moved reports whether param was a previous slug and the caller should redirect.
+// A param that looks like an ObjectID but matches no document is still looked up as a slug.
+func findBySlugOrID(ctx context.Context, collection *mongo.Collection, param string, filter bson.M, out interface{}) (moved bool, err error) {
+	query := func(key string, value interface{}) bson.M {
+		q := bson.M{key: value}
+		for k, v := range filter {
+			q[k] = v
+		}
+		return q
+	}
+
+	if id, err := primitive.ObjectIDFromHex(param); err == nil {
+		err := collection.FindOne(ctx, query("_id", id)).Decode(out)
+		if err != mongo.ErrNoDocuments {
+			return false, err
+		}
+	}
+
+	err = collection.FindOne(ctx, query("slug", param)).Decode(out)
+	if err != mongo.ErrNoDocuments {
+		return false, err
+	}
+
+	if err := collection.FindOne(ctx, query("previous_slugs", param)).Decode(out); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// redirectToSlug permanently redirects a request addressed by an old slug to the current slug
+func redirectToSlug(c *fiber.Ctx, oldSlug, currentSlug string) error {
+	path := c.Path()
+	if i := strings.LastIndex(path, "/"+oldSlug); i >= 0 {
+		path = path[:i+1] + currentSlug + path[i+1+len(oldSlug):]
+	}
+	if query := c.Context().QueryArgs().String(); query != "" {
+		path += "?" + query
+	}
+	return c.Redirect(path, fiber.StatusMovedPermanently)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"myfiberproject/database"
+	"myfiberproject/libs"
+	"myfiberproject/models"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func tagCollection() *mongo.Collection {
+	return database.GetMongoClient().Database(database.GetDatabaseName()).Collection("tags")
+}
+
+// resolveTags makes sure every tag name has a document in the tags collection and returns the
+// canonical names, so that "go lang" and "Go Lang" end up as the same tag on the article.
+func resolveTags(ctx context.Context, names []string) ([]string, error) {
+	collection := tagCollection()
+	resolved := []string{}
+	seen := map[string]bool{}
+
+	for _, name := range names {
+		name = strings.Join(strings.Fields(name), " ")
+		slug := libs.Slugify(name)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+
+		var tag models.Tag
+		err := collection.FindOneAndUpdate(
+			ctx,
+			bson.M{"slug": slug},
+			bson.M{"$setOnInsert": bson.M{
+				"_id":         primitive.NewObjectID(),
+				"name":        name,
+				"slug":        slug,
+				"usage_count": 0,
+				"created_at":  time.Now(),
+				"updated_at":  time.Now(),
+			}},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		).Decode(&tag)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, tag.Name)
+	}
+
+	return resolved, nil
+}
+
+// refreshTagUsage recounts how many articles use each of the given tag names
+func refreshTagUsage(ctx context.Context, names ...[]string) error {
+	contentCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("article_content")
+	collection := tagCollection()
+	seen := map[string]bool{}
+
+	for _, group := range names {
+		for _, name := range group {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			count, err := contentCollection.CountDocuments(ctx, bson.M{"tags": name})
+			if err != nil {
+				return err
+			}
+			_, err = collection.UpdateOne(ctx, bson.M{"name": name}, bson.M{
+				"$set": bson.M{"usage_count": count, "updated_at": time.Now()},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"myfiberproject/config"
+	"myfiberproject/database"
+	"myfiberproject/libs"
+	"myfiberproject/middleware"
+	"myfiberproject/models"
+	"os"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Sessions use two tokens: a short-lived JWT access token sent with every request, and an opaque
+// refresh token that is exchanged at POST /token/refresh for a new pair. Refresh tokens rotate on
+// every use, and presenting one that was already used revokes its whole family.
+
+var errRefreshTokenInvalid = errors.New("invalid or expired refresh token")
+var errRefreshTokenReused = errors.New("refresh token was already used, the session has been revoked")
+
+// SessionTokens is the token pair returned by Login, Signup and RefreshToken
+type SessionTokens struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"` // Empty for users pending approval, who cannot refresh
+	ExpiresIn    int    `json:"expires_in"`    // Seconds until the access token expires
+}
+
+func refreshTokenCollection() *mongo.Collection {
+	return database.GetMongoClient().Database(database.GetDatabaseName()).Collection("refresh_tokens")
+}
+
+func accessTokenTTL() time.Duration {
+	return time.Duration(config.GetEnvInt("ACCESS_TOKEN_TTL_MINUTES", 15)) * time.Minute
+}
+
+// refreshTokenTTL is the lifetime of a refresh token; RememberMe selects the longer one
+func refreshTokenTTL(rememberMe bool) time.Duration {
+	if rememberMe {
+		return time.Duration(config.GetEnvInt("REMEMBER_ME_REFRESH_TOKEN_TTL_DAYS", 30)) * 24 * time.Hour
+	}
+	return time.Duration(config.GetEnvInt("REFRESH_TOKEN_TTL_HOURS", 24)) * time.Hour
+}
+
+// sessionDeadline is when a session ends however often it is refreshed, SESSION_MAX_DAYS after login
+func sessionDeadline(session models.RefreshToken) time.Time {
+	startedAt := session.SessionStartedAt
+	if startedAt.IsZero() {
+		// Sessions started before session details were recorded begin at their oldest remaining token
+		startedAt = session.CreatedAt
+	}
+	return startedAt.Add(time.Duration(config.GetEnvInt("SESSION_MAX_DAYS", 90)) * 24 * time.Hour)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomToken returns a URL-safe random string with 256 bits of entropy
+func randomToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// issueAccessToken signs a short-lived access token for a session of the user. Every token gets a
+// unique ID so that it can be revoked on its own.
+func issueAccessToken(user models.User, sessionID primitive.ObjectID, mfa bool) (string, error) {
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if jwtSecret == "" {
+		return "", errors.New("JWT_SECRET environment variable not set")
+	}
+
+	now := time.Now()
+	claims := middleware.CustomClaims{
+		UserID:    user.ID.Hex(),
+		Role:      string(user.Role),
+		Status:    string(user.Status),
+		SessionID: sessionID.Hex(),
+		MFA:       mfa,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        primitive.NewObjectID().Hex(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenTTL())),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(jwtSecret))
+}
+
+// issueRefreshToken stores the hash of the next refresh token of a session and returns the token.
+// session carries the family, user and client fields; ID, hash and times are filled in here. The
+// token expires after refreshTokenTTL, or at the session deadline if that comes first.
+func issueRefreshToken(ctx context.Context, session models.RefreshToken) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	session.ID = primitive.NewObjectID()
+	session.TokenHash = hashToken(token)
+	session.UsedAt = nil
+	session.RevokedAt = nil
+	session.CreatedAt = now
+	session.ExpiresAt = now.Add(refreshTokenTTL(session.RememberMe))
+	if deadline := sessionDeadline(session); deadline.Before(session.ExpiresAt) {
+		session.ExpiresAt = deadline
+	}
+	if _, err := refreshTokenCollection().InsertOne(ctx, session); err != nil {
+		return "", fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return token, nil
+}
+
+// issueSessionTokens issues an access token and the next refresh token of a session
+func issueSessionTokens(ctx context.Context, user models.User, session models.RefreshToken) (SessionTokens, error) {
+	accessToken, err := issueAccessToken(user, session.FamilyID, session.MFA)
+	if err != nil {
+		return SessionTokens{}, err
+	}
+	refreshToken, err := issueRefreshToken(ctx, session)
+	if err != nil {
+		return SessionTokens{}, err
+	}
+	return SessionTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(accessTokenTTL().Seconds()),
+	}, nil
+}
+
+// startSession starts a new refresh token family for a user who just authenticated from the
+// client making the request. mfa records whether a second factor was verified. RefreshToken refuses
+// users pending approval, so they only get an access token and log in again once approved.
+func startSession(ctx context.Context, c *fiber.Ctx, user models.User, rememberMe, mfa bool) (SessionTokens, error) {
+	session := models.RefreshToken{
+		FamilyID:         primitive.NewObjectID(),
+		UserID:           user.ID,
+		RememberMe:       rememberMe,
+		MFA:              mfa,
+		SessionStartedAt: time.Now(),
+	}
+	if user.Status != models.Approved {
+		accessToken, err := issueAccessToken(user, session.FamilyID, mfa)
+		if err != nil {
+			return SessionTokens{}, err
+		}
+		return SessionTokens{AccessToken: accessToken, ExpiresIn: int(accessTokenTTL().Seconds())}, nil
+	}
+	setSessionClient(c, &session)
+	return issueSessionTokens(ctx, user, session)
+}
+
+// setSessionClient records the client a session was last used from
+func setSessionClient(c *fiber.Ctx, session *models.RefreshToken) {
+	session.IP = c.IP()
+	session.UserAgent = c.Get(fiber.HeaderUserAgent)
+	session.Device = libs.DescribeDevice(session.UserAgent)
+}
+
+// rotateRefreshToken marks a refresh token as used and returns it. A token that was used before is
+// treated as stolen: the whole family is revoked and errRefreshTokenReused is returned.
+func rotateRefreshToken(ctx context.Context, token string) (models.RefreshToken, error) {
+	collection := refreshTokenCollection()
+	now := time.Now()
+
+	var stored models.RefreshToken
+	err := collection.FindOne(ctx, bson.M{"token_hash": hashToken(token)}).Decode(&stored)
+	if err == mongo.ErrNoDocuments {
+		return stored, errRefreshTokenInvalid
+	}
+	if err != nil {
+		return stored, err
+	}
+	// Tokens issued before the session deadline was enforced may outlive it
+	if stored.RevokedAt != nil || !stored.ExpiresAt.After(now) || !sessionDeadline(stored).After(now) {
+		return stored, errRefreshTokenInvalid
+	}
+
+	// Claim the token; the filter fails when another request used it first
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": stored.ID, "used_at": bson.M{"$exists": false}, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"used_at": now}},
+	)
+	if err != nil {
+		return stored, err
+	}
+	if result.ModifiedCount == 0 {
+		// A used token coming back means it leaked, so the access tokens of the session go too
+		if err := endSession(ctx, stored.FamilyID); err != nil {
+			return stored, err
+		}
+		return stored, errRefreshTokenReused
+	}
+	return stored, nil
+}
+
+// revokeRefreshTokenFamily revokes every refresh token issued from the same login
+func revokeRefreshTokenFamily(ctx context.Context, familyID primitive.ObjectID) error {
+	_, err := refreshTokenCollection().UpdateMany(ctx,
+		bson.M{"family_id": familyID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	return err
+}
+
+// endSession revokes a session: its refresh tokens and the access tokens issued for it
+func endSession(ctx context.Context, familyID primitive.ObjectID) error {
+	if err := revokeRefreshTokenFamily(ctx, familyID); err != nil {
+		return err
+	}
+	return middleware.RevokeSession(ctx, familyID.Hex(), time.Now().Add(accessTokenTTL()))
+}
+
+// endAllSessions revokes every session of a user
+func endAllSessions(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := refreshTokenCollection().UpdateMany(ctx,
+		bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	return middleware.RevokeUserTokens(ctx, userID.Hex(), time.Now().Add(accessTokenTTL()))
+}
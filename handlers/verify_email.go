@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"myfiberproject/config"
+	"myfiberproject/database"
+	"myfiberproject/libs"
+	"myfiberproject/models"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Verification tokens are JWTs signed with a key derived from JWT_SECRET, so they can never be
+// mistaken for access tokens. They carry the address being verified and stop working when the
+// user's email changes.
+
+const emailVerificationAudience = "email-verification"
+
+const resendVerificationMessage = "If a pending account uses this email address and it is not verified yet, a new verification link has been sent to it."
+
+type emailVerificationClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+func emailVerificationKey() ([]byte, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil, errors.New("JWT_SECRET environment variable not set")
+	}
+	return []byte(emailVerificationAudience + ":" + secret), nil
+}
+
+func emailVerificationTTL() time.Duration {
+	return time.Duration(config.GetEnvInt("EMAIL_VERIFICATION_TTL_HOURS", 48)) * time.Hour
+}
+
+// sendVerificationEmail emails the user a link to verify their address
+func sendVerificationEmail(ctx context.Context, user models.User) error {
+	key, err := emailVerificationKey()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, emailVerificationClaims{
+		Email: user.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   user.ID.Hex(),
+			Audience:  jwt.ClaimStrings{emailVerificationAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(emailVerificationTTL())),
+		},
+	}).SignedString(key)
+	if err != nil {
+		return err
+	}
+
+	link := frontendLink("/verify-email", url.Values{"token": {token}})
+	return sendMail(ctx, libs.Mail{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Text: fmt.Sprintf("Hello %s,\n\nPlease confirm your email address by opening the link below. It expires in %d hours.\n\n%s\n\nIf you did not sign up, you can ignore this email.\n",
+			user.FullName, int(emailVerificationTTL().Hours()), link),
+	})
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// VerifyEmail marks the user's email address as verified with a token sent by sendVerificationEmail
+func VerifyEmail(c *fiber.Ctx) error {
+	var request VerifyEmailRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+	}
+	if validationErr := validate.Struct(&request); validationErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": validationErr.Error()})
+	}
+
+	key, err := emailVerificationKey()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	var claims emailVerificationClaims
+	token, err := jwt.ParseWithClaims(request.Token, &claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return key, nil
+	})
+	if err != nil || !token.Valid || !claims.VerifyAudience(emailVerificationAudience, true) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired verification token"})
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.Subject)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired verification token"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The email must still be the one the token was issued for
+	userCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("users")
+	result, err := userCollection.UpdateOne(ctx,
+		bson.M{"_id": userID, "email": claims.Email, "email_verified_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"email_verified_at": time.Now(), "updated_at": time.Now()}, "$inc": incrementVersion},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+	if result.MatchedCount == 0 {
+		count, err := userCollection.CountDocuments(ctx, bson.M{"_id": userID, "email": claims.Email})
+		if err != nil || count == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired verification token"})
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Email address verified"})
+}
+
+// ResendVerificationEmail sends a new verification link to a pending user who has not verified
+// their address yet. The response does not reveal whether such a user exists.
+func ResendVerificationEmail(c *fiber.Ctx) error {
+	type request struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	var req request
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+	}
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": validationErr.Error()})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	userCollection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("users")
+	var user models.User
+	err := userCollection.FindOne(ctx, bson.M{
+		"email":             req.Email,
+		"status":            models.Pending,
+		"email_verified_at": bson.M{"$exists": false},
+	}).Decode(&user)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+	if err == nil {
+		if err := sendVerificationEmail(ctx, user); err != nil {
+			log.Printf("Failed to send verification email to %s: %v", user.Email, err)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": resendVerificationMessage})
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Articles and users carry a version that every write increments. Reads return it as the ETag, and
+// updates must send it back in If-Match so that a stale copy cannot overwrite a newer one.
+
+var errVersionMismatch = fmt.Errorf("the resource was modified since it was read, fetch it again and retry")
+
+// incrementVersion is added to every update of a versioned document
+var incrementVersion = bson.M{"version": 1}
+
+func versionETag(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// setVersionETag sets the ETag header of a response carrying a versioned document
+func setVersionETag(c *fiber.Ctx, version int64) {
+	c.Set(fiber.HeaderETag, versionETag(version))
+}
+
+// checkIfMatch compares the If-Match header with the current version. It sends 428 when the header
+// is missing and 412 when it does not match, and returns false in both cases. If-Match uses the strong
+// comparison of RFC 9110, so weak validators (W/"...") never match.
+func checkIfMatch(c *fiber.Ctx, version int64) (bool, error) {
+	ifMatch := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if ifMatch == "" {
+		return false, c.Status(fiber.StatusPreconditionRequired).JSON(fiber.Map{"error": "If-Match header with the ETag of the resource is required"})
+	}
+	if ifMatch == "*" {
+		return true, nil
+	}
+
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		if tag == versionETag(version) {
+			return true, nil
+		}
+		// Accept the bare version number too
+		if n, err := strconv.ParseInt(strings.Trim(tag, `"`), 10, 64); err == nil && n == version {
+			return true, nil
+		}
+	}
+	return false, versionConflict(c, version)
+}
+
+// versionConflict sends 412 with the current ETag, so that the client knows what to fetch
+func versionConflict(c *fiber.Ctx, version int64) error {
+	setVersionETag(c, version)
+	return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{"error": errVersionMismatch.Error()})
+}
+
+// versionFilter matches the document only while it still has the given version. Documents written
+// before versioning have no version field and count as version 0.
+func versionFilter(id primitive.ObjectID, version int64) bson.M {
+	if version == 0 {
+		return bson.M{"_id": id, "$or": bson.A{bson.M{"version": 0}, bson.M{"version": bson.M{"$exists": false}}}}
+	}
+	return bson.M{"_id": id, "version": version}
+}
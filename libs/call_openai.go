@@ -22,7 +22,7 @@ func CallOpenAI(apiKey, prompt string) (OpenAIResponse, error) {
 	payload := map[string]interface{}{
 		"model": "gpt-4o-2024-11-20", // Use GPT-4o model
 		"messages": []map[string]string{
-			{"role": "system", "content": "You are an AI that helps suggest relevant article categories based on content."},
+			{"role": "system", "content": "You are an AI that helps suggest relevant article categories and tags based on content."},
 			{"role": "user", "content": prompt},
 		},
 		"temperature": 0.7,
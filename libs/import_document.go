@@ -0,0 +1,128 @@
+package libs
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ImportedDocument is an article extracted from an uploaded file
+type ImportedDocument struct {
+	Title   string
+	Excerpt string
+	Tags    []string
+	Content string
+	Format  string // "markdown" or "html"
+	Images  []ImportedImage
+}
+
+// ImportedImage is an image embedded in or referenced by an imported document
+type ImportedImage struct {
+	Ref      string // Reference to the image in Content, to be replaced by the URL of the stored image
+	FileName string
+	Data     []byte
+}
+
+// ResolveFunc loads a file referenced by a relative path in a document, e.g. from the same zip archive
+type ResolveFunc func(path string) ([]byte, bool)
+
+// ImportBudget limits how much is extracted from compressed files during one import, so that a small
+// upload cannot expand into an enormous one. It is shared by a zip archive and the .docx files in it.
+type ImportBudget struct {
+	maxFile   int64
+	remaining int64
+}
+
+// NewImportBudget allows extracting files of up to maxFile bytes, and maxTotal bytes altogether
+func NewImportBudget(maxFile, maxTotal int64) *ImportBudget {
+	return &ImportBudget{maxFile: maxFile, remaining: maxTotal}
+}
+
+// Extract reads an extracted file, failing when it is larger than a file may be or when it
+// exhausts the budget
+func (b *ImportBudget) Extract(name string, r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, min(b.maxFile, b.remaining)+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > b.maxFile {
+		return nil, fmt.Errorf("%s is larger than %d bytes", name, b.maxFile)
+	}
+	if int64(len(data)) > b.remaining {
+		return nil, fmt.Errorf("%s expands beyond the size allowed for one import", name)
+	}
+	b.remaining -= int64(len(data))
+	return data, nil
+}
+
+// ImportFile extracts a document from a Markdown (.md), HTML (.html) or Word (.docx) file.
+// resolve may be nil when the file was uploaded on its own. budget limits what a .docx file
+// expands to.
+func ImportFile(name string, data []byte, resolve ResolveFunc, budget *ImportBudget) (ImportedDocument, error) {
+	if resolve == nil {
+		resolve = func(string) ([]byte, bool) { return nil, false }
+	}
+
+	var document ImportedDocument
+	var err error
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown":
+		document, err = importMarkdown(data, resolve)
+	case ".html", ".htm":
+		document, err = importHTML(data, resolve)
+	case ".docx":
+		document, err = importDOCX(data, budget)
+	default:
+		return ImportedDocument{}, fmt.Errorf("unsupported file type: %s", path.Ext(name))
+	}
+	if err != nil {
+		return ImportedDocument{}, err
+	}
+
+	// Fall back to the file name when the document has no title of its own
+	if strings.TrimSpace(document.Title) == "" {
+		document.Title = strings.TrimSuffix(path.Base(name), path.Ext(name))
+	}
+	if strings.TrimSpace(document.Content) == "" {
+		return ImportedDocument{}, fmt.Errorf("document has no content")
+	}
+	return document, nil
+}
+
+// IsImportableFile reports whether ImportFile supports the file
+func IsImportableFile(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown", ".html", ".htm", ".docx":
+		return true
+	}
+	return false
+}
+
+// loadImage resolves an image reference found in a document: inline data URIs are decoded
+// and relative paths are loaded through resolve. Remote URLs are left alone.
+func loadImage(ref string, resolve ResolveFunc) (ImportedImage, bool) {
+	if strings.HasPrefix(ref, "data:") {
+		meta, encoded, found := strings.Cut(strings.TrimPrefix(ref, "data:"), ",")
+		if !found || !strings.HasSuffix(meta, ";base64") {
+			return ImportedImage{}, false
+		}
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return ImportedImage{}, false
+		}
+		return ImportedImage{Ref: ref, FileName: "image", Data: data}, true
+	}
+
+	u, err := url.Parse(ref)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return ImportedImage{}, false
+	}
+	data, ok := resolve(u.Path)
+	if !ok {
+		return ImportedImage{}, false
+	}
+	return ImportedImage{Ref: ref, FileName: path.Base(u.Path), Data: data}, true
+}
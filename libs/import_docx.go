@@ -0,0 +1,227 @@
+package libs
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+type docxRelationship struct {
+	ID         string `xml:"Id,attr"`
+	Target     string `xml:"Target,attr"`
+	TargetMode string `xml:"TargetMode,attr"`
+}
+
+// importDOCX converts a Word document to Markdown. Paragraph styles map to headings, bold and italic
+// runs to emphasis, hyperlinks to links and embedded pictures to images. A "Title" styled paragraph,
+// or failing that the first level 1 heading, becomes the title. Parts are extracted within budget.
+func importDOCX(data []byte, budget *ImportBudget) (ImportedDocument, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return ImportedDocument{}, fmt.Errorf("invalid docx file: %v", err)
+	}
+
+	files := map[string]*zip.File{}
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+	readPart := func(name string) ([]byte, error) {
+		file, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("missing %s", name)
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return budget.Extract(name, rc)
+	}
+
+	documentXML, err := readPart("word/document.xml")
+	if err != nil {
+		return ImportedDocument{}, fmt.Errorf("invalid docx file: %v", err)
+	}
+
+	relationships := map[string]docxRelationship{}
+	if _, ok := files["word/_rels/document.xml.rels"]; ok {
+		relsXML, err := readPart("word/_rels/document.xml.rels")
+		if err != nil {
+			return ImportedDocument{}, fmt.Errorf("invalid docx file: %v", err)
+		}
+		var rels struct {
+			Relationships []docxRelationship `xml:"Relationship"`
+		}
+		if err := xml.Unmarshal(relsXML, &rels); err == nil {
+			for _, rel := range rels.Relationships {
+				relationships[rel.ID] = rel
+			}
+		}
+	}
+
+	document := ImportedDocument{Format: "markdown"}
+	images := map[string]string{} // Reference in Content by part name, as pictures can be repeated
+	paragraphs := []string{}
+	firstHeading := -1
+
+	var paragraph, run strings.Builder
+	var style, link string
+	var bold, italic, inRunProperties bool
+
+	flushRun := func() {
+		text := run.String()
+		run.Reset()
+		if strings.TrimSpace(text) == "" {
+			paragraph.WriteString(text)
+			return
+		}
+		if italic {
+			text = "*" + text + "*"
+		}
+		if bold {
+			text = "**" + text + "**"
+		}
+		paragraph.WriteString(text)
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(documentXML))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return ImportedDocument{}, fmt.Errorf("invalid docx document: %v", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				paragraph.Reset()
+				style = ""
+			case "pStyle":
+				style = docxAttr(t, "val")
+			case "r":
+				bold, italic = false, false
+			case "rPr":
+				inRunProperties = true
+			case "b", "i":
+				if inRunProperties {
+					on := docxAttr(t, "val") != "0" && docxAttr(t, "val") != "false"
+					if t.Name.Local == "b" {
+						bold = on
+					} else {
+						italic = on
+					}
+				}
+			case "t":
+				var text string
+				if err := decoder.DecodeElement(&text, &t); err != nil {
+					return ImportedDocument{}, fmt.Errorf("invalid docx document: %v", err)
+				}
+				run.WriteString(text)
+			case "tab":
+				run.WriteString("\t")
+			case "br":
+				run.WriteString("\n")
+			case "hyperlink":
+				if rel, ok := relationships[docxAttr(t, "id")]; ok && rel.TargetMode == "External" {
+					link = rel.Target
+					flushRun()
+					paragraph.WriteString("[")
+				}
+			case "blip":
+				rel, ok := relationships[docxAttr(t, "embed")]
+				if !ok {
+					continue
+				}
+				name := path.Join("word", rel.Target)
+				ref, ok := images[name]
+				if !ok {
+					if _, ok := files[name]; !ok {
+						continue
+					}
+					data, err := readPart(name)
+					if err != nil {
+						return ImportedDocument{}, fmt.Errorf("invalid docx file: %v", err)
+					}
+					ref = "docx-image:" + rel.ID
+					images[name] = ref
+					document.Images = append(document.Images, ImportedImage{Ref: ref, FileName: path.Base(name), Data: data})
+				}
+				flushRun()
+				paragraph.WriteString("![](" + ref + ")")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "rPr":
+				inRunProperties = false
+			case "r":
+				flushRun()
+			case "hyperlink":
+				if link != "" {
+					flushRun()
+					paragraph.WriteString("](" + link + ")")
+					link = ""
+				}
+			case "p":
+				text := strings.TrimSpace(paragraph.String())
+				if text == "" {
+					continue
+				}
+				level := docxHeadingLevel(style)
+				switch {
+				case strings.EqualFold(style, "Title") && document.Title == "":
+					document.Title = text
+				case level > 0:
+					if level == 1 && firstHeading < 0 {
+						firstHeading = len(paragraphs)
+					}
+					paragraphs = append(paragraphs, strings.Repeat("#", level)+" "+text)
+				default:
+					paragraphs = append(paragraphs, text)
+				}
+			}
+		}
+	}
+
+	if document.Title == "" && firstHeading >= 0 {
+		document.Title = strings.TrimLeft(paragraphs[firstHeading], "# ")
+		paragraphs = append(paragraphs[:firstHeading], paragraphs[firstHeading+1:]...)
+	}
+	document.Content = strings.Join(paragraphs, "\n\n") + "\n"
+
+	return document, nil
+}
+
+// docxHeadingLevel maps paragraph styles such as "Heading1" to a Markdown heading level
+func docxHeadingLevel(style string) int {
+	lower := strings.ToLower(style)
+	if !strings.HasPrefix(lower, "heading") {
+		return 0
+	}
+	level, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(lower, "heading")))
+	if err != nil || level < 1 {
+		return 0
+	}
+	if level > 6 {
+		level = 6
+	}
+	return level
+}
+
+// docxAttr returns an attribute by local name, ignoring the namespace prefix
+func docxAttr(element xml.StartElement, local string) string {
+	for _, a := range element.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
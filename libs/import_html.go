@@ -0,0 +1,102 @@
+package libs
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// importHTML reads an HTML document. The title comes from <title> or, failing that, from the first
+// <h1>, which is then removed from the body. Scripts and styles are dropped.
+func importHTML(data []byte, resolve ResolveFunc) (ImportedDocument, error) {
+	root, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return ImportedDocument{}, err
+	}
+
+	document := ImportedDocument{Format: "html"}
+	var body, firstHeading *html.Node
+	var removals []*html.Node
+	seen := map[string]bool{}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Title:
+				document.Title = strings.TrimSpace(textContent(n))
+			case atom.Body:
+				body = n
+			case atom.H1:
+				if firstHeading == nil {
+					firstHeading = n
+				}
+			case atom.Script, atom.Style, atom.Noscript:
+				removals = append(removals, n)
+				return
+			case atom.Meta:
+				if attr(n, "name") == "description" {
+					document.Excerpt = attr(n, "content")
+				}
+			case atom.Img:
+				if ref := attr(n, "src"); ref != "" && !seen[ref] {
+					seen[ref] = true
+					if image, ok := loadImage(ref, resolve); ok {
+						document.Images = append(document.Images, image)
+					}
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	for _, n := range removals {
+		n.Parent.RemoveChild(n)
+	}
+	if document.Title == "" && firstHeading != nil {
+		document.Title = strings.TrimSpace(textContent(firstHeading))
+		firstHeading.Parent.RemoveChild(firstHeading)
+	}
+
+	if body == nil {
+		body = root
+	}
+	var buf bytes.Buffer
+	for child := body.FirstChild; child != nil; child = child.NextSibling {
+		if err := html.Render(&buf, child); err != nil {
+			return ImportedDocument{}, err
+		}
+	}
+	document.Content = strings.TrimSpace(buf.String()) + "\n"
+
+	return document, nil
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return b.String()
+}
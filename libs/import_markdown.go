@@ -0,0 +1,99 @@
+package libs
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	markdownImagePattern   = regexp.MustCompile(`!\[[^\]]*\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	markdownHeadingPattern = regexp.MustCompile(`(?m)^#\s+(.+?)\s*#*\s*$`)
+)
+
+// importMarkdown reads a Markdown document with optional front matter. The title comes from the
+// front matter or, failing that, from a leading level 1 heading, which is then removed from the body.
+func importMarkdown(data []byte, resolve ResolveFunc) (ImportedDocument, error) {
+	frontMatter, body := SplitFrontMatter(strings.ReplaceAll(string(data), "\r\n", "\n"))
+
+	document := ImportedDocument{
+		Title:   frontMatter["title"],
+		Excerpt: frontMatter["excerpt"],
+		Tags:    parseFrontMatterList(frontMatter["tags"]),
+		Format:  "markdown",
+	}
+	if document.Excerpt == "" {
+		document.Excerpt = frontMatter["description"]
+	}
+
+	if document.Title == "" {
+		if match := markdownHeadingPattern.FindStringSubmatchIndex(body); match != nil && strings.TrimSpace(body[:match[0]]) == "" {
+			document.Title = body[match[2]:match[3]]
+			body = body[match[1]:]
+		}
+	}
+	document.Content = strings.TrimSpace(body) + "\n"
+
+	seen := map[string]bool{}
+	for _, match := range markdownImagePattern.FindAllStringSubmatch(document.Content, -1) {
+		ref := match[1]
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		if image, ok := loadImage(ref, resolve); ok {
+			document.Images = append(document.Images, image)
+		}
+	}
+
+	return document, nil
+}
+
+// SplitFrontMatter separates a leading "---" delimited front matter block from a Markdown document.
+// Only flat "key: value" lines are supported; quotes around values are removed.
+func SplitFrontMatter(text string) (map[string]string, string) {
+	values := map[string]string{}
+	if !strings.HasPrefix(text, "---\n") {
+		return values, text
+	}
+
+	end := strings.Index(text[4:], "\n---")
+	if end < 0 {
+		return values, text
+	}
+	block := text[4 : 4+end]
+	rest := strings.TrimPrefix(text[4+end+4:], "\n")
+
+	for _, line := range strings.Split(block, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found || strings.HasPrefix(line, " ") {
+			continue
+		}
+		values[strings.ToLower(strings.TrimSpace(key))] = unquoteFrontMatter(strings.TrimSpace(value))
+	}
+	return values, rest
+}
+
+func unquoteFrontMatter(value string) string {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted
+		}
+	}
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+	}
+	return value
+}
+
+// parseFrontMatterList parses an inline list such as "[go, web]" or "go, web"
+func parseFrontMatterList(value string) []string {
+	value = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(value), "["), "]")
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = unquoteFrontMatter(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
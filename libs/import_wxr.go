@@ -0,0 +1,220 @@
+package libs
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The WXR namespaces carry the export version (1.0, 1.1 or 1.2), so elements are matched on their
+// local name. content:encoded and excerpt:encoded share a local name and are told apart by namespace.
+const wxrContentNamespace = "http://purl.org/rss/1.0/modules/content/"
+
+// WXRTerm is a category or tag read from a WXR file
+type WXRTerm struct {
+	Slug   string
+	Name   string
+	Parent string // Slug of the parent category, if any
+}
+
+// WXRPost is a post, page or attachment read from a WXR file
+type WXRPost struct {
+	ID            int
+	ParentID      int
+	Type          string // "post", "page", "attachment", ...
+	Status        string // "publish", "draft", "pending", "private", "future", "trash", ...
+	Title         string
+	Slug          string
+	Content       string // HTML
+	Excerpt       string
+	Date          time.Time
+	Modified      time.Time
+	AttachmentURL string
+	Categories    []WXRTerm
+	Tags          []WXRTerm
+	Meta          map[string]string
+}
+
+// WXRExport holds the content of a WXR file
+type WXRExport struct {
+	Title      string
+	Categories []WXRTerm
+	Tags       []WXRTerm
+	Posts      []WXRPost
+}
+
+type wxrDocument struct {
+	Channel struct {
+		Title      string `xml:"title"`
+		Categories []struct {
+			XMLName  xml.Name
+			Nicename string `xml:"category_nicename"`
+			Parent   string `xml:"category_parent"`
+			Name     string `xml:"cat_name"`
+		} `xml:"category"`
+		Tags []struct {
+			Slug string `xml:"tag_slug"`
+			Name string `xml:"tag_name"`
+		} `xml:"tag"`
+		Items []struct {
+			Title   string `xml:"title"`
+			PubDate string `xml:"pubDate"`
+			Encoded []struct {
+				XMLName xml.Name
+				Value   string `xml:",chardata"`
+			} `xml:"encoded"`
+			PostID        string `xml:"post_id"`
+			PostDate      string `xml:"post_date"`
+			PostDateGMT   string `xml:"post_date_gmt"`
+			Modified      string `xml:"post_modified"`
+			ModifiedGMT   string `xml:"post_modified_gmt"`
+			Name          string `xml:"post_name"`
+			Status        string `xml:"status"`
+			Parent        string `xml:"post_parent"`
+			Type          string `xml:"post_type"`
+			AttachmentURL string `xml:"attachment_url"`
+			Categories    []struct {
+				Domain   string `xml:"domain,attr"`
+				Nicename string `xml:"nicename,attr"`
+				Name     string `xml:",chardata"`
+			} `xml:"category"`
+			PostMeta []struct {
+				Key   string `xml:"meta_key"`
+				Value string `xml:"meta_value"`
+			} `xml:"postmeta"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+// ParseWXR reads a WordPress export file
+func ParseWXR(data []byte) (*WXRExport, error) {
+	var document wxrDocument
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("invalid WXR file: %v", err)
+	}
+
+	channel := document.Channel
+	export := &WXRExport{Title: strings.TrimSpace(channel.Title)}
+
+	for _, category := range channel.Categories {
+		// RSS allows plain <category> elements in the channel as well; only wp:category are terms
+		if !strings.Contains(category.XMLName.Space, "wordpress.org/export") {
+			continue
+		}
+		export.Categories = append(export.Categories, WXRTerm{
+			Slug:   wxrSlug(category.Nicename),
+			Name:   strings.TrimSpace(category.Name),
+			Parent: wxrSlug(category.Parent),
+		})
+	}
+	for _, tag := range channel.Tags {
+		export.Tags = append(export.Tags, WXRTerm{Slug: wxrSlug(tag.Slug), Name: strings.TrimSpace(tag.Name)})
+	}
+
+	for _, item := range channel.Items {
+		post := WXRPost{
+			ID:            wxrInt(item.PostID),
+			ParentID:      wxrInt(item.Parent),
+			Type:          strings.TrimSpace(item.Type),
+			Status:        strings.TrimSpace(item.Status),
+			Title:         strings.TrimSpace(item.Title),
+			Slug:          wxrSlug(item.Name),
+			AttachmentURL: strings.TrimSpace(item.AttachmentURL),
+			Date:          wxrDate(item.PostDateGMT, item.PostDate, item.PubDate),
+			Meta:          map[string]string{},
+		}
+		post.Modified = wxrDate(item.ModifiedGMT, item.Modified)
+		if post.Modified.IsZero() || post.Modified.Before(post.Date) {
+			post.Modified = post.Date
+		}
+
+		for _, encoded := range item.Encoded {
+			switch {
+			case encoded.XMLName.Space == wxrContentNamespace:
+				post.Content = wxrParagraphs(encoded.Value)
+			case strings.Contains(encoded.XMLName.Space, "excerpt"):
+				post.Excerpt = strings.TrimSpace(encoded.Value)
+			}
+		}
+
+		for _, category := range item.Categories {
+			term := WXRTerm{Slug: wxrSlug(category.Nicename), Name: strings.TrimSpace(category.Name)}
+			switch category.Domain {
+			case "category":
+				post.Categories = append(post.Categories, term)
+			case "post_tag":
+				post.Tags = append(post.Tags, term)
+			}
+		}
+		for _, meta := range item.PostMeta {
+			post.Meta[strings.TrimSpace(meta.Key)] = strings.TrimSpace(meta.Value)
+		}
+
+		export.Posts = append(export.Posts, post)
+	}
+
+	return export, nil
+}
+
+// wxrBlockTag matches content that starts with a block-level HTML element
+var wxrBlockTag = regexp.MustCompile(`(?i)^<(?:p|div|h[1-6]|ul|ol|li|blockquote|pre|table|figure|hr|img|iframe|!--)[\s>/]`)
+
+// wxrParagraphs wraps loose text in paragraphs. WordPress stores classic editor content with blank
+// lines between paragraphs and only adds the <p> elements when the post is displayed.
+func wxrParagraphs(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.Contains(content, "\n\n") {
+		return strings.TrimSpace(content)
+	}
+
+	var paragraphs []string
+	for _, chunk := range strings.Split(content, "\n\n") {
+		chunk = strings.TrimSpace(chunk)
+		switch {
+		case chunk == "":
+			continue
+		case wxrBlockTag.MatchString(chunk):
+			paragraphs = append(paragraphs, chunk)
+		default:
+			paragraphs = append(paragraphs, "<p>"+strings.ReplaceAll(chunk, "\n", "<br>\n")+"</p>")
+		}
+	}
+	return strings.Join(paragraphs, "\n")
+}
+
+// wxrSlug decodes the percent-encoded slugs WordPress uses for non-ASCII titles
+func wxrSlug(slug string) string {
+	slug = strings.TrimSpace(slug)
+	if unescaped, err := url.PathUnescape(slug); err == nil {
+		return unescaped
+	}
+	return slug
+}
+
+func wxrInt(value string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(value))
+	return n
+}
+
+// wxrDate returns the first of the values that parses. WordPress writes "0000-00-00 00:00:00" for
+// posts that were never published, which does not parse and is skipped.
+func wxrDate(values ...string) time.Time {
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if t, err := time.Parse(WXRDateFormat, value); err == nil && t.Year() > 1 {
+			return t
+		}
+		if t, err := time.Parse(time.RFC1123Z, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
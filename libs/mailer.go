@@ -0,0 +1,171 @@
+package libs
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Mail is a plain text email message
+type Mail struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+// Mailer delivers email. The driver is chosen with MAIL_DRIVER, see NewMailer.
+type Mailer interface {
+	Send(ctx context.Context, mail Mail) error
+}
+
+// MailerConfig holds the settings of every driver
+type MailerConfig struct {
+	Driver       string // "smtp" or "log"
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string // Leave empty for servers without authentication, such as MailHog
+	SMTPPassword string
+}
+
+// NewMailer returns the mailer for the configured driver
+func NewMailer(config MailerConfig) (Mailer, error) {
+	switch config.Driver {
+	case "smtp":
+		if config.SMTPHost == "" || config.From == "" {
+			return nil, errors.New("the smtp mail driver needs SMTP_HOST and MAIL_FROM")
+		}
+		from, err := mail.ParseAddress(config.From)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAIL_FROM: %v", err)
+		}
+		return &SMTPMailer{config: config, from: from}, nil
+	case "log", "":
+		return LogMailer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", config.Driver)
+	}
+}
+
+// LogMailer writes messages to the log instead of sending them, for development
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, message Mail) error {
+	log.Printf("Mail to %s\nSubject: %s\n\n%s", message.To, message.Subject, message.Text)
+	return nil
+}
+
+// SMTPMailer sends messages through an SMTP server, using STARTTLS when the server offers it
+type SMTPMailer struct {
+	config MailerConfig
+	from   *mail.Address
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, message Mail) error {
+	to, err := mail.ParseAddress(message.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient: %v", err)
+	}
+	data, err := buildMessage(m.from.String(), message)
+	if err != nil {
+		return err
+	}
+
+	// smtp.SendMail takes no context, so the connection is dialed here and closed once ctx is done,
+	// which makes a stalled server fail the pending command instead of leaving it running
+	host := m.config.SMTPHost
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, m.config.SMTPPort))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	err = m.send(conn, host, to.Address, data)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// send runs the SMTP conversation of smtp.SendMail on an open connection
+func (m *SMTPMailer) send(conn net.Conn, host, to string, data []byte) error {
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if m.config.SMTPUsername != "" {
+		auth := smtp.PlainAuth("", m.config.SMTPUsername, m.config.SMTPPassword, host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(m.from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildMessage formats the headers and the quoted-printable body of a message
+func buildMessage(from string, message Mail) ([]byte, error) {
+	// Line breaks in a header value would let the value add headers of its own
+	for _, value := range []string{from, message.To, message.Subject} {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, errors.New("mail header contains a line break")
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", message.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	body := quotedprintable.NewWriter(&b)
+	if _, err := body.Write([]byte(strings.ReplaceAll(message.Text, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,185 @@
+package libs
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime/quotedprintable"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpStub is a minimal SMTP server that accepts one message and records the envelope and data
+type smtpStub struct {
+	listener net.Listener
+	from     string
+	to       []string
+	data     string
+	done     chan struct{}
+}
+
+func startSMTPStub(t *testing.T) *smtpStub {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub := &smtpStub{listener: listener, done: make(chan struct{})}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		defer close(stub.done)
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		text := textproto.NewConn(conn)
+		text.PrintfLine("220 stub ready")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch command {
+			case "EHLO", "HELO":
+				text.PrintfLine("250-stub")
+				text.PrintfLine("250 8BITMIME")
+			case "MAIL":
+				stub.from = line
+				text.PrintfLine("250 OK")
+			case "RCPT":
+				stub.to = append(stub.to, line)
+				text.PrintfLine("250 OK")
+			case "DATA":
+				text.PrintfLine("354 send the message")
+				data, err := text.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				stub.data = string(data)
+				text.PrintfLine("250 OK")
+			case "QUIT":
+				text.PrintfLine("221 bye")
+				return
+			default:
+				text.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+	return stub
+}
+
+func newTestSMTPMailer(t *testing.T, addr string) Mailer {
+	t.Helper()
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mailer, err := NewMailer(MailerConfig{Driver: "smtp", From: "News Desk <news@example.com>", SMTPHost: host, SMTPPort: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mailer
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	stub := startSMTPStub(t)
+	mailer := newTestSMTPMailer(t, stub.listener.Addr().String())
+
+	text := "Héllo Ünïcode reader,\n" + strings.Repeat("long line ", 12) + "\nvisit https://example.com/?a=b"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := mailer.Send(ctx, Mail{To: "Reader <reader@example.com>", Subject: "Café news", Text: text})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	<-stub.done
+
+	if !strings.HasPrefix(stub.from, "MAIL FROM:<news@example.com>") {
+		t.Errorf("MAIL command = %q", stub.from)
+	}
+	if len(stub.to) != 1 || stub.to[0] != "RCPT TO:<reader@example.com>" {
+		t.Errorf("RCPT commands = %q", stub.to)
+	}
+
+	// ReadDotBytes turns CRLF into LF
+	header, body, found := strings.Cut(stub.data, "\n\n")
+	if !found {
+		t.Fatalf("message has no header/body separator:\n%s", stub.data)
+	}
+	for _, want := range []string{
+		`From: "News Desk" <news@example.com>`,
+		"To: Reader <reader@example.com>",
+		"Subject: =?utf-8?q?Caf=C3=A9_news?=",
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Transfer-Encoding: quoted-printable",
+	} {
+		if !strings.Contains(header+"\n", want+"\n") {
+			t.Errorf("header %q missing in:\n%s", want, header)
+		}
+	}
+	if !strings.Contains(header, "\nDate: ") {
+		t.Errorf("Date header missing in:\n%s", header)
+	}
+
+	if !strings.Contains(body, "H=C3=A9llo =C3=9Cn=C3=AFcode reader,") {
+		t.Errorf("body is not quoted-printable encoded:\n%s", body)
+	}
+	for _, line := range strings.Split(body, "\n") {
+		if len(line) > 76 {
+			t.Errorf("quoted-printable line longer than 76 characters: %q", line)
+		}
+	}
+	decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(strings.ReplaceAll(body, "\n", "\r\n"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The DATA writer ends the message with a line break before the terminating dot
+	if got := strings.TrimSuffix(strings.ReplaceAll(string(decoded), "\r\n", "\n"), "\n"); got != text {
+		t.Errorf("decoded body = %q, want %q", got, text)
+	}
+}
+
+func TestSMTPMailerSendStopsWhenContextIsDone(t *testing.T) {
+	// A server that accepts the connection but never greets the client
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		bufio.NewReader(conn).ReadString('\n')
+	}()
+
+	mailer := newTestSMTPMailer(t, listener.Addr().String())
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = mailer.Send(ctx, Mail{To: "reader@example.com", Subject: "Hello", Text: "Hello"})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Send error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("Send returned after %v", elapsed)
+	}
+}
+
+func TestSMTPMailerRejectsHeaderInjection(t *testing.T) {
+	mailer := newTestSMTPMailer(t, "127.0.0.1:25")
+	err := mailer.Send(context.Background(), Mail{To: "reader@example.com", Subject: "Hello\r\nBcc: other@example.com", Text: "Hello"})
+	if err == nil {
+		t.Fatal("Send accepted a subject with a line break")
+	}
+}
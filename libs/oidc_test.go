@@ -0,0 +1,313 @@
+package libs
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	testClientID     = "cms"
+	testClientSecret = "s3cret"
+	testRedirectURL  = "https://cms.example.com/auth/oidc/callback"
+)
+
+// testIssuer is an OpenID provider that hands out one authorization code at a time
+type testIssuer struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+
+	mu        sync.Mutex
+	challenge string                 // PKCE challenge of the pending authorization
+	claims    map[string]interface{} // Claims of the next ID token, on top of the defaults
+	signer    func(claims jwt.MapClaims) string
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &testIssuer{t: t, key: key, kid: "key-1"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer.server.URL,
+			"authorization_endpoint": issuer.server.URL + "/authorize",
+			"token_endpoint":         issuer.server.URL + "/token",
+			"jwks_uri":               issuer.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		issuer.mu.Lock()
+		defer issuer.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"kid": issuer.kid,
+			"n":   base64.RawURLEncoding.EncodeToString(issuer.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(issuer.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", issuer.token)
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (i *testIssuer) token(w http.ResponseWriter, r *http.Request) {
+	tokenError := func(code string) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": code})
+	}
+	if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "authorization_code" || r.Form.Get("code") != "the-code" {
+		tokenError("invalid_grant")
+		return
+	}
+	if r.Form.Get("redirect_uri") != testRedirectURL {
+		tokenError("invalid_grant")
+		return
+	}
+	if user, password, ok := r.BasicAuth(); !ok || user != testClientID || password != testClientSecret {
+		tokenError("invalid_client")
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != i.challenge {
+		tokenError("invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   i.server.URL,
+		"sub":   "user-42",
+		"aud":   testClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Minute).Unix(),
+		"email": "ada@example.com",
+		"name":  "Ada Lovelace",
+	}
+	for name, value := range i.claims {
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]string{"access_token": "opaque", "token_type": "Bearer", "id_token": i.sign(claims)})
+}
+
+func (i *testIssuer) sign(claims jwt.MapClaims) string {
+	if i.signer != nil {
+		return i.signer(claims)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = i.kid
+	signed, err := token.SignedString(i.key)
+	if err != nil {
+		i.t.Fatal(err)
+	}
+	return signed
+}
+
+func (i *testIssuer) provider(t *testing.T) *OIDCProvider {
+	t.Helper()
+	provider, err := DiscoverOIDC(context.Background(), OIDCConfig{
+		IssuerURL:    i.server.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"openid", "email"},
+	})
+	if err != nil {
+		t.Fatalf("DiscoverOIDC: %v", err)
+	}
+	return provider
+}
+
+// authorize starts a login and returns the PKCE verifier, with the provider remembering the
+// challenge of the authorization URL the way a real provider would
+func (i *testIssuer) authorize(t *testing.T, provider *OIDCProvider, nonce string, claims map[string]interface{}) string {
+	t.Helper()
+	verifier, challenge, err := NewPKCEVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := url.Parse(provider.AuthCodeURL("the-state", nonce, challenge))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := authURL.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("client_id") != testClientID || query.Get("state") != "the-state" {
+		t.Fatalf("unexpected authorization URL %s", authURL)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.challenge = query.Get("code_challenge")
+	i.claims = map[string]interface{}{"nonce": nonce}
+	for name, value := range claims {
+		i.claims[name] = value
+	}
+	return verifier
+}
+
+func TestOIDCExchange(t *testing.T) {
+	issuer := newTestIssuer(t)
+	provider := issuer.provider(t)
+	if provider.TokenEndpoint != issuer.server.URL+"/token" {
+		t.Fatalf("token endpoint = %q", provider.TokenEndpoint)
+	}
+
+	verifier := issuer.authorize(t, provider, "nonce-1", map[string]interface{}{
+		"email_verified": "true", // Sent as a string by some providers
+		"groups":         []string{"cms-admins", "press"},
+		"amr":            []string{"pwd", "mfa"},
+	})
+	claims, err := provider.Exchange(context.Background(), "the-code", verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if claims.Subject != "user-42" || claims.Email != "ada@example.com" || !claims.EmailVerified || claims.Name != "Ada Lovelace" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if strings.Join(claims.Groups, ",") != "cms-admins,press" {
+		t.Errorf("groups = %q", claims.Groups)
+	}
+	if strings.Join(claims.AMR, ",") != "pwd,mfa" {
+		t.Errorf("amr = %q", claims.AMR)
+	}
+}
+
+func TestOIDCExchangeRejectsInvalidTokens(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		claims   map[string]interface{}
+		signer   func(issuer *testIssuer) func(jwt.MapClaims) string
+		nonce    string // Nonce expected by the relying party, "nonce-1" when empty
+		verifier string // PKCE verifier sent with the token request, the right one when empty
+	}{
+		{name: "wrong nonce", nonce: "nonce-2"},
+		{name: "wrong PKCE verifier", verifier: "not-the-verifier"},
+		{name: "wrong audience", claims: map[string]interface{}{"aud": "other-client"}},
+		{name: "wrong authorized party", claims: map[string]interface{}{"aud": []string{testClientID, "other-client"}, "azp": "other-client"}},
+		{name: "wrong issuer", claims: map[string]interface{}{"iss": "https://evil.example.com"}},
+		{name: "expired", claims: map[string]interface{}{"exp": time.Now().Add(-time.Minute).Unix()}},
+		{name: "no expiry", claims: map[string]interface{}{"exp": nil}},
+		{name: "no subject", claims: map[string]interface{}{"sub": nil}},
+		{name: "HS256 signature", signer: func(issuer *testIssuer) func(jwt.MapClaims) string {
+			return func(claims jwt.MapClaims) string {
+				signed, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("guessable"))
+				return signed
+			}
+		}},
+		{name: "signed with an unknown key", signer: func(issuer *testIssuer) func(jwt.MapClaims) string {
+			return func(claims jwt.MapClaims) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+				token.Header["kid"] = issuer.kid
+				signed, _ := token.SignedString(otherKey)
+				return signed
+			}
+		}},
+		{name: "unknown key ID", signer: func(issuer *testIssuer) func(jwt.MapClaims) string {
+			return func(claims jwt.MapClaims) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+				token.Header["kid"] = "key-2"
+				signed, _ := token.SignedString(issuer.key)
+				return signed
+			}
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			issuer := newTestIssuer(t)
+			provider := issuer.provider(t)
+			if test.signer != nil {
+				issuer.signer = test.signer(issuer)
+			}
+
+			verifier := issuer.authorize(t, provider, "nonce-1", test.claims)
+			if test.verifier != "" {
+				verifier = test.verifier
+			}
+			nonce := test.nonce
+			if nonce == "" {
+				nonce = "nonce-1"
+			}
+			if claims, err := provider.Exchange(context.Background(), "the-code", verifier, nonce); err == nil {
+				t.Fatalf("Exchange accepted the token: %+v", claims)
+			}
+		})
+	}
+}
+
+func TestOIDCKeyRotation(t *testing.T) {
+	issuer := newTestIssuer(t)
+	provider := issuer.provider(t)
+
+	verifier := issuer.authorize(t, provider, "nonce-1", nil)
+	if _, err := provider.Exchange(context.Background(), "the-code", verifier, "nonce-1"); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	// A new key ID makes the key set be fetched again, once jwksRefreshInterval has passed
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.mu.Lock()
+	issuer.key, issuer.kid = newKey, "key-2"
+	issuer.mu.Unlock()
+	provider.keysMu.Lock()
+	provider.keysFetchedAt = time.Now().Add(-jwksRefreshInterval)
+	provider.keysMu.Unlock()
+
+	verifier = issuer.authorize(t, provider, "nonce-2", nil)
+	if _, err := provider.Exchange(context.Background(), "the-code", verifier, "nonce-2"); err != nil {
+		t.Fatalf("Exchange after key rotation: %v", err)
+	}
+}
+
+func TestDiscoverOIDCRejectsIssuerMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 "https://evil.example.com",
+			"authorization_endpoint": "https://evil.example.com/authorize",
+			"token_endpoint":         "https://evil.example.com/token",
+			"jwks_uri":               "https://evil.example.com/jwks",
+		})
+	}))
+	defer server.Close()
+
+	_, err := DiscoverOIDC(context.Background(), OIDCConfig{IssuerURL: server.URL, ClientID: testClientID, RedirectURL: testRedirectURL})
+	if err == nil || !strings.Contains(err.Error(), "issuer") {
+		t.Fatalf("DiscoverOIDC error = %v, want an issuer mismatch", err)
+	}
+}
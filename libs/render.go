@@ -0,0 +1,70 @@
+package libs
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+var (
+	// Raw HTML inside Markdown is let through by goldmark and cleaned up by the sanitizer afterwards
+	markdown = goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+	)
+
+	// htmlPolicy is the allowlist of elements and attributes that may appear in rendered content
+	htmlPolicy = newHTMLPolicy()
+)
+
+func newHTMLPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	// Keep the language hint of code blocks for syntax highlighting on the website
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+-]+$`)).OnElements("code")
+	return policy
+}
+
+// RenderContent converts content written in the given format ("markdown", "html" or "plain") to sanitized HTML
+func RenderContent(format, content string) (string, error) {
+	switch format {
+	case "markdown":
+		var buf bytes.Buffer
+		if err := markdown.Convert([]byte(content), &buf); err != nil {
+			return "", fmt.Errorf("failed to render markdown: %v", err)
+		}
+		return SanitizeHTML(buf.String()), nil
+	case "html":
+		return SanitizeHTML(content), nil
+	case "plain", "":
+		return renderPlainText(content), nil
+	default:
+		return "", fmt.Errorf("unsupported content format: %s", format)
+	}
+}
+
+// SanitizeHTML removes every element and attribute that is not on the allowlist, preventing stored XSS
+func SanitizeHTML(content string) string {
+	return htmlPolicy.Sanitize(content)
+}
+
+// renderPlainText escapes plain text and turns blank-line separated blocks into paragraphs
+func renderPlainText(content string) string {
+	var b strings.Builder
+	for _, paragraph := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br>"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
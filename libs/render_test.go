@@ -0,0 +1,141 @@
+package libs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string // Fragments the output must contain
+		notWant []string // Fragments the output must not contain
+	}{
+		{
+			name:    "script element",
+			input:   `<p>Hello</p><script>alert(1)</script>`,
+			want:    []string{"<p>Hello</p>"},
+			notWant: []string{"<script", "alert(1)"},
+		},
+		{
+			name:    "event handler attributes",
+			input:   `<img src="/media/a.png" onerror="alert(1)"><p onclick="alert(2)">Hi</p>`,
+			want:    []string{`<img src="/media/a.png">`, "<p>Hi</p>"},
+			notWant: []string{"onerror", "onclick", "alert"},
+		},
+		{
+			name:    "javascript links",
+			input:   `<a href="javascript:alert(1)">click</a> <a href="JaVaScRiPt:alert(2)">again</a>`,
+			want:    []string{"click", "again"},
+			notWant: []string{"javascript:", "JaVaScRiPt:"},
+		},
+		{
+			name:  "safe links are kept",
+			input: `<a href="https://example.com/page">link</a>`,
+			want:  []string{`href="https://example.com/page"`, "link</a>"},
+		},
+		{
+			name:    "iframes and styles",
+			input:   `<iframe src="https://evil.example.com"></iframe><style>body{display:none}</style><p style="position:fixed">x</p>`,
+			want:    []string{"<p>x</p>"},
+			notWant: []string{"<iframe", "<style", "display:none", "position:fixed"},
+		},
+		{
+			name:    "code language class is kept, other classes are not",
+			input:   `<pre><code class="language-go">x := 1</code></pre><p class="evil">y</p>`,
+			want:    []string{`<code class="language-go">`, "<p>y</p>"},
+			notWant: []string{`class="evil"`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := SanitizeHTML(test.input)
+			for _, fragment := range test.want {
+				if !strings.Contains(got, fragment) {
+					t.Errorf("SanitizeHTML(%q) = %q, missing %q", test.input, got, fragment)
+				}
+			}
+			for _, fragment := range test.notWant {
+				if strings.Contains(got, fragment) {
+					t.Errorf("SanitizeHTML(%q) = %q, contains %q", test.input, got, fragment)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		input   string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:   "markdown",
+			format: "markdown",
+			input:  "# Title\n\nSome **bold** text and a [link](https://example.com).",
+			want:   []string{"<h1>Title</h1>", "<strong>bold</strong>", `<a href="https://example.com"`},
+		},
+		{
+			name:    "raw HTML inside markdown",
+			format:  "markdown",
+			input:   "Intro\n\n<script>alert(1)</script>\n\n<div onmouseover=\"alert(2)\">hover</div>\n\n<a href=\"javascript:alert(3)\">x</a>",
+			want:    []string{"<p>Intro</p>", "hover"},
+			notWant: []string{"<script", "alert(1)", "onmouseover", "javascript:"},
+		},
+		{
+			name:    "markdown javascript link",
+			format:  "markdown",
+			input:   "[click](javascript:alert(1))",
+			want:    []string{"click"},
+			notWant: []string{"javascript:"},
+		},
+		{
+			name:    "html",
+			format:  "html",
+			input:   `<h2>Title</h2><p onclick="alert(1)">Body</p><script>alert(2)</script>`,
+			want:    []string{"<h2>Title</h2>", "<p>Body</p>"},
+			notWant: []string{"onclick", "<script", "alert"},
+		},
+		{
+			name:    "plain text is escaped",
+			format:  "plain",
+			input:   "First <b>line</b>\nsecond line\n\nNext paragraph",
+			want:    []string{"<p>First &lt;b&gt;line&lt;/b&gt;<br>second line</p>", "<p>Next paragraph</p>"},
+			notWant: []string{"<b>"},
+		},
+		{
+			name:   "empty format is plain text",
+			format: "",
+			input:  "<script>alert(1)</script>",
+			want:   []string{"<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := RenderContent(test.format, test.input)
+			if err != nil {
+				t.Fatalf("RenderContent: %v", err)
+			}
+			for _, fragment := range test.want {
+				if !strings.Contains(got, fragment) {
+					t.Errorf("RenderContent(%q, %q) = %q, missing %q", test.format, test.input, got, fragment)
+				}
+			}
+			for _, fragment := range test.notWant {
+				if strings.Contains(got, fragment) {
+					t.Errorf("RenderContent(%q, %q) = %q, contains %q", test.format, test.input, got, fragment)
+				}
+			}
+		})
+	}
+
+	if _, err := RenderContent("rtf", "x"); err == nil {
+		t.Error("RenderContent accepted an unknown format")
+	}
+}
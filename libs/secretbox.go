@@ -0,0 +1,54 @@
+package libs
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+// SealString encrypts a short secret for storage with AES-256-GCM. The key is hashed to 256 bits,
+// so any passphrase can be used. The result holds the nonce and the ciphertext, base64 encoded.
+func SealString(key, plaintext string) (string, error) {
+	aead, err := newSecretBox(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenString decrypts a value produced by SealString with the same key
+func OpenString(key, sealed string) (string, error) {
+	aead, err := newSecretBox(key)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.RawStdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", errors.New("invalid sealed value")
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("invalid sealed value")
+	}
+	return string(plaintext), nil
+}
+
+func newSecretBox(key string) (cipher.AEAD, error) {
+	if key == "" {
+		return nil, errors.New("encryption key is empty")
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,55 @@
+package libs
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// MaxSitemapURLs is the number of URLs a single sitemap may list according to the sitemaps protocol
+const MaxSitemapURLs = 50000
+
+type SitemapURL struct {
+	Loc     string
+	LastMod time.Time
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name         `xml:"urlset"`
+	XMLNS   string           `xml:"xmlns,attr"`
+	URLs    []sitemapURLNode `xml:"url"`
+}
+
+type sitemapURLNode struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name         `xml:"sitemapindex"`
+	XMLNS    string           `xml:"xmlns,attr"`
+	Sitemaps []sitemapURLNode `xml:"sitemap"`
+}
+
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+func sitemapNodes(urls []SitemapURL) []sitemapURLNode {
+	nodes := make([]sitemapURLNode, 0, len(urls))
+	for _, u := range urls {
+		node := sitemapURLNode{Loc: u.Loc}
+		if !u.LastMod.IsZero() {
+			node.LastMod = u.LastMod.UTC().Format(time.RFC3339)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// RenderSitemap renders a <urlset> sitemap document
+func RenderSitemap(urls []SitemapURL) ([]byte, error) {
+	return marshalXML(sitemapURLSet{XMLNS: sitemapNamespace, URLs: sitemapNodes(urls)})
+}
+
+// RenderSitemapIndex renders a <sitemapindex> document pointing at the given sitemaps
+func RenderSitemapIndex(sitemaps []SitemapURL) ([]byte, error) {
+	return marshalXML(sitemapIndex{XMLNS: sitemapNamespace, Sitemaps: sitemapNodes(sitemaps)})
+}
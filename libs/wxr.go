@@ -0,0 +1,166 @@
+package libs
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// WXR (WordPress eXtended RSS) is the format used by the WordPress importer and exporter
+
+const wxrHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"
+	xmlns:excerpt="http://wordpress.org/export/1.2/excerpt/"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	xmlns:wfw="http://wellformedweb.org/CommentAPI/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/"
+	xmlns:wp="http://wordpress.org/export/1.2/">
+<channel>
+`
+
+// WXRDateFormat is the format of wp:post_date and the other WordPress date fields
+const WXRDateFormat = "2006-01-02 15:04:05"
+
+type CDATA struct {
+	Value string `xml:",cdata"`
+}
+
+type WXRSite struct {
+	Title       string
+	Link        string
+	Description string
+	Language    string
+	Version     string // Defaults to 1.2
+	BaseSiteURL string
+	BaseBlogURL string
+}
+
+type WXRCategory struct {
+	XMLName  xml.Name `xml:"wp:category"`
+	TermID   int      `xml:"wp:term_id"`
+	Nicename string   `xml:"wp:category_nicename"`
+	Parent   string   `xml:"wp:category_parent"`
+	Name     CDATA    `xml:"wp:cat_name"`
+}
+
+type WXRTag struct {
+	XMLName xml.Name `xml:"wp:tag"`
+	TermID  int      `xml:"wp:term_id"`
+	Slug    string   `xml:"wp:tag_slug"`
+	Name    CDATA    `xml:"wp:tag_name"`
+}
+
+type WXRItemCategory struct {
+	Domain   string `xml:"domain,attr"` // "category" or "post_tag"
+	Nicename string `xml:"nicename,attr"`
+	Name     string `xml:",cdata"`
+}
+
+type WXRPostMeta struct {
+	Key   CDATA `xml:"wp:meta_key"`
+	Value CDATA `xml:"wp:meta_value"`
+}
+
+type WXRGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type WXRItem struct {
+	XMLName       xml.Name          `xml:"item"`
+	Title         string            `xml:"title"`
+	Link          string            `xml:"link"`
+	PubDate       string            `xml:"pubDate"`
+	Creator       CDATA             `xml:"dc:creator"`
+	GUID          WXRGUID           `xml:"guid"`
+	Description   string            `xml:"description"`
+	Content       CDATA             `xml:"content:encoded"`
+	Excerpt       CDATA             `xml:"excerpt:encoded"`
+	PostID        int               `xml:"wp:post_id"`
+	PostDate      string            `xml:"wp:post_date"`
+	PostDateGMT   string            `xml:"wp:post_date_gmt"`
+	Modified      string            `xml:"wp:post_modified"`
+	ModifiedGMT   string            `xml:"wp:post_modified_gmt"`
+	CommentStatus string            `xml:"wp:comment_status"`
+	PingStatus    string            `xml:"wp:ping_status"`
+	Name          string            `xml:"wp:post_name"`
+	Status        string            `xml:"wp:status"`
+	ParentID      int               `xml:"wp:post_parent"`
+	MenuOrder     int               `xml:"wp:menu_order"`
+	Type          string            `xml:"wp:post_type"`
+	Password      string            `xml:"wp:post_password"`
+	IsSticky      int               `xml:"wp:is_sticky"`
+	AttachmentURL string            `xml:"wp:attachment_url,omitempty"`
+	Categories    []WXRItemCategory `xml:"category"`
+	PostMeta      []WXRPostMeta     `xml:"wp:postmeta"`
+}
+
+// WXRWriter streams a WXR document, one element at a time
+type WXRWriter struct {
+	w       io.Writer
+	encoder *xml.Encoder
+}
+
+// NewWXRWriter writes the document header and the channel information
+func NewWXRWriter(w io.Writer, site WXRSite) (*WXRWriter, error) {
+	if _, err := io.WriteString(w, wxrHeader); err != nil {
+		return nil, err
+	}
+
+	writer := &WXRWriter{w: w, encoder: xml.NewEncoder(w)}
+	writer.encoder.Indent("", "\t")
+
+	if site.Version == "" {
+		site.Version = "1.2"
+	}
+	// The channel fields are written inline rather than wrapped in an element of their own
+	for _, field := range []struct{ name, value string }{
+		{"title", site.Title},
+		{"link", site.Link},
+		{"description", site.Description},
+		{"language", site.Language},
+		{"wp:wxr_version", site.Version},
+		{"wp:base_site_url", site.BaseSiteURL},
+		{"wp:base_blog_url", site.BaseBlogURL},
+	} {
+		if err := writer.write(struct {
+			XMLName xml.Name
+			Value   string `xml:",chardata"`
+		}{XMLName: xml.Name{Local: field.name}, Value: field.value}); err != nil {
+			return nil, err
+		}
+	}
+	return writer, nil
+}
+
+// write encodes an element; the encoder's indentation puts each one on a new line
+func (w *WXRWriter) write(element interface{}) error {
+	return w.encoder.Encode(element)
+}
+
+func (w *WXRWriter) WriteCategory(category WXRCategory) error {
+	return w.write(category)
+}
+
+func (w *WXRWriter) WriteTag(tag WXRTag) error {
+	return w.write(tag)
+}
+
+func (w *WXRWriter) WriteItem(item WXRItem) error {
+	if item.CommentStatus == "" {
+		item.CommentStatus = "closed"
+	}
+	if item.PingStatus == "" {
+		item.PingStatus = "closed"
+	}
+	return w.write(item)
+}
+
+// Close ends the document. It does not close the underlying writer.
+func (w *WXRWriter) Close() error {
+	if err := w.encoder.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprint(w.w, "\n</channel>\n</rss>\n")
+	return err
+}
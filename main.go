@@ -4,6 +4,7 @@ import (
 	"log"
 	"myfiberproject/config"
 	"myfiberproject/database"
+	"myfiberproject/handlers"
 	"myfiberproject/routes"
 	"os"
 
@@ -20,14 +21,20 @@ func main() {
 		log.Fatalf("Failed to connect to MongoDB: %v", err)
 	}
 
+	// Resume the AI enrichment of articles queued before the last shutdown
+	go handlers.RequeueArticleEnrichment()
+
 	app := fiber.New(fiber.Config{
 		BodyLimit: 25 * 1024 * 1024, // 25 MB
 	})
 
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "http://localhost:3000, http://localhost:3001",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-		AllowMethods: "GET, POST, HEAD, PUT, DELETE, PATCH",
+		AllowOrigins:  "http://localhost:3000, http://localhost:3001",
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, Idempotency-Key, If-Match",
+		ExposeHeaders: "ETag",
+		AllowMethods:  "GET, POST, HEAD, PUT, DELETE, PATCH",
+		// Lets POST /auth/oidc/link set the cookie its callback checks
+		AllowCredentials: true,
 	}))
 
 	routes.SetupRoutes(app)
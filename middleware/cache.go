@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CacheControl lets browsers and shared caches keep successful or not-modified GET responses for maxAge
+func CacheControl(maxAge time.Duration) fiber.Handler {
+	value := fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+
+	return func(c *fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		status := c.Response().StatusCode()
+		if c.Method() == fiber.MethodGet && (status == fiber.StatusOK || status == fiber.StatusNotModified) {
+			c.Set(fiber.HeaderCacheControl, value)
+		}
+		return nil
+	}
+}
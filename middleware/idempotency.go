@@ -0,0 +1,219 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"myfiberproject/database"
+	"myfiberproject/models"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+	// idempotencyLease is how long a request holds its key without renewing it. A running request renews
+	// the lease every idempotencyHeartbeat, however long it takes, so a request that crashed or never
+	// stored its response stops blocking retries soon after it stopped.
+	idempotencyLease     = 2 * time.Minute
+	idempotencyHeartbeat = idempotencyLease / 4
+	// idempotencyWriteTimeout bounds each database operation on the key
+	idempotencyWriteTimeout = 10 * time.Second
+)
+
+// Idempotency makes POST requests carrying an Idempotency-Key header safe to retry. The first request
+// with a key runs normally and its response is stored for ttl; repeating it returns the stored response
+// without running the handler again. Reusing a key with a different body is rejected. Keys are scoped
+// to the user and route, so it must come after RequireRole.
+func Idempotency(ttl time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get("Idempotency-Key")
+		if key == "" || c.Method() != fiber.MethodPost {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Idempotency-Key is too long"})
+		}
+
+		userID := ""
+		if claims, ok := c.Locals("claims").(*CustomClaims); ok {
+			userID = claims.UserID
+		}
+		requestHash, err := hashIdempotentRequest(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot read request"})
+		}
+
+		collection := database.GetMongoClient().Database(database.GetDatabaseName()).Collection("idempotency_keys")
+		ctx, cancel := context.WithTimeout(context.Background(), idempotencyWriteTimeout)
+		defer cancel()
+
+		now := time.Now()
+		record := models.IdempotencyKey{
+			ID:          hashStrings(userID, c.Path(), key),
+			RequestHash: requestHash,
+			LeaseID:     primitive.NewObjectID().Hex(),
+			LockedUntil: now.Add(idempotencyLease),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(ttl),
+		}
+
+		// Claim the key; the insert fails if an earlier request already holds it
+		if _, err := collection.InsertOne(ctx, record); err != nil {
+			if !mongo.IsDuplicateKeyError(err) {
+				log.Println("Failed to store idempotency key:", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to store idempotency key"})
+			}
+
+			// Take the key over when the request holding it has stopped without storing a response
+			result, err := collection.UpdateOne(ctx, bson.M{
+				"_id":          record.ID,
+				"request_hash": requestHash,
+				"status_code":  0,
+				"locked_until": bson.M{"$not": bson.M{"$gte": now}},
+			}, bson.M{"$set": bson.M{
+				"lease_id":     record.LeaseID,
+				"locked_until": record.LockedUntil,
+				"expires_at":   record.ExpiresAt,
+			}})
+			if err != nil {
+				log.Println("Failed to take over idempotency key:", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to store idempotency key"})
+			}
+			if result.ModifiedCount == 0 {
+				var existing models.IdempotencyKey
+				if err := collection.FindOne(ctx, bson.M{"_id": record.ID}).Decode(&existing); err != nil {
+					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to read idempotency key"})
+				}
+				return replayIdempotentResponse(c, existing, requestHash)
+			}
+		}
+		cancel()
+
+		stopHeartbeat := keepIdempotencyLease(collection, record.ID, record.LeaseID)
+		handlerErr := c.Next()
+		stopHeartbeat()
+
+		// The handler may have run for longer than the write timeout, so the response gets a context of its own
+		ctx, cancel = context.WithTimeout(context.Background(), idempotencyWriteTimeout)
+		defer cancel()
+		held := bson.M{"_id": record.ID, "lease_id": record.LeaseID}
+
+		// Server errors are not stored, so that the client can retry with the same key
+		status := c.Response().StatusCode()
+		if handlerErr != nil || status >= fiber.StatusInternalServerError {
+			if _, err := collection.DeleteOne(ctx, held); err != nil {
+				log.Println("Failed to release idempotency key:", err)
+			}
+			return handlerErr
+		}
+
+		_, err = collection.UpdateOne(ctx, held, bson.M{"$set": bson.M{
+			"status_code":  status,
+			"content_type": string(c.Response().Header.ContentType()),
+			"body":         c.Response().Body(),
+		}})
+		if err != nil {
+			log.Println("Failed to store idempotent response:", err)
+		}
+		return nil
+	}
+}
+
+// keepIdempotencyLease renews the lease on a key in the background until the returned function is called
+func keepIdempotencyLease(collection *mongo.Collection, id, leaseID string) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(idempotencyHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), idempotencyWriteTimeout)
+				_, err := collection.UpdateOne(ctx, bson.M{"_id": id, "lease_id": leaseID},
+					bson.M{"$set": bson.M{"locked_until": time.Now().Add(idempotencyLease)}})
+				cancel()
+				if err != nil {
+					log.Println("Failed to renew idempotency key lease:", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func replayIdempotentResponse(c *fiber.Ctx, existing models.IdempotencyKey, requestHash string) error {
+	if existing.RequestHash != requestHash {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Idempotency-Key was already used for a different request"})
+	}
+	if existing.StatusCode == 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A request with this Idempotency-Key is still being processed"})
+	}
+
+	c.Set("Idempotent-Replayed", "true")
+	if existing.ContentType != "" {
+		c.Set(fiber.HeaderContentType, existing.ContentType)
+	}
+	return c.Status(existing.StatusCode).Send(existing.Body)
+}
+
+// hashIdempotentRequest fingerprints the request body. Multipart forms are hashed by their fields and
+// files rather than their raw bytes, because clients pick a new boundary on every attempt.
+func hashIdempotentRequest(c *fiber.Ctx) (string, error) {
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		return hashStrings(string(c.Body())), nil
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return "", err
+	}
+
+	var parts []string
+	for name, values := range form.Value {
+		for _, value := range values {
+			parts = append(parts, "value:"+name+"="+value)
+		}
+	}
+	for name, headers := range form.File {
+		for _, header := range headers {
+			file, err := header.Open()
+			if err != nil {
+				return "", err
+			}
+			hash := sha256.New()
+			_, err = io.Copy(hash, file)
+			file.Close()
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, "file:"+name+"="+header.Filename+":"+hex.EncodeToString(hash.Sum(nil)))
+		}
+	}
+	sort.Strings(parts)
+	return hashStrings(parts...), nil
+}
+
+func hashStrings(values ...string) string {
+	hash := sha256.New()
+	for _, value := range values {
+		hash.Write([]byte(value))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
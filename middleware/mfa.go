@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"myfiberproject/config"
+	"strings"
+)
+
+// MFARequired reports whether a role may only be used from sessions that verified a second factor.
+// MFA_REQUIRED_ROLES is a comma separated list of roles, administrator by default.
+func MFARequired(role string) bool {
+	for _, required := range strings.Split(config.GetEnv("MFA_REQUIRED_ROLES", "administrator"), ",") {
+		if strings.TrimSpace(required) == role {
+			return true
+		}
+	}
+	return false
+}
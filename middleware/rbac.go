@@ -11,8 +11,11 @@ import (
 const InvalidTokenError = "Invalid token"
 
 type CustomClaims struct {
-	Role   string `json:"role"`
-	Status string `json:"status"`
+	UserID    string `json:"id"`
+	Role      string `json:"role"`
+	Status    string `json:"status"`
+	SessionID string `json:"sid,omitempty"` // Refresh token family the token was issued for
+	MFA       bool   `json:"mfa,omitempty"` // Whether the session was authenticated with a second factor
 	jwt.RegisteredClaims
 }
 
@@ -22,22 +25,19 @@ func RequireRole(requiredRoles []string, requiredStatus string) fiber.Handler {
 			return c.Next()
 		}
 
-		tokenString, err := getTokenFromHeader(c)
-		if err != nil {
+		claims, ok, err := authenticate(c)
+		if !ok {
 			return err
 		}
 
-		claims, err := parseToken(c, tokenString)
-		if err != nil {
-			return err
-		}
-
-		// Check if claims is nil before passing it to isRoleAllowed
-		if claims == nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": InvalidTokenError})
-		}
-
 		if isRoleAllowed(claims, requiredRoles, requiredStatus) {
+			if MFARequired(claims.Role) && !claims.MFA {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error":        "Multi-factor authentication is required for this role, enroll at POST /mfa/totp and log in again",
+					"mfa_required": true,
+				})
+			}
+			c.Locals("claims", claims) // Make the claims available to the handlers
 			return c.Next()
 		}
 
@@ -45,6 +45,53 @@ func RequireRole(requiredRoles []string, requiredStatus string) fiber.Handler {
 	}
 }
 
+// RequireAuth accepts any valid token, whatever the role and status of the user
+func RequireAuth() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok, err := authenticate(c)
+		if !ok {
+			return err
+		}
+
+		c.Locals("claims", claims) // Make the claims available to the handlers
+		return c.Next()
+	}
+}
+
+// authenticate parses the bearer token and checks it against the revocation list. When ok is false
+// the error response has already been sent.
+func authenticate(c *fiber.Ctx) (claims *CustomClaims, ok bool, err error) {
+	tokenString, err := getTokenFromHeader(c)
+	if err != nil {
+		return nil, false, err
+	}
+
+	claims, err = parseToken(c, tokenString)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Check if claims is nil before using them
+	if claims == nil {
+		return nil, false, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": InvalidTokenError})
+	}
+
+	// Tokens without an ID or expiry predate revocation support and cannot be revoked
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil, false, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": InvalidTokenError})
+	}
+
+	revoked, err := isTokenRevoked(claims)
+	if err != nil {
+		return nil, false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to check token"})
+	}
+	if revoked {
+		return nil, false, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token has been revoked"})
+	}
+
+	return claims, true, nil
+}
+
 func isPublicAccess(requiredRoles []string, requiredStatus string) bool {
 	return len(requiredRoles) == 1 && requiredRoles[0] == "public" && requiredStatus == ""
 }
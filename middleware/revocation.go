@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"myfiberproject/config"
+	"myfiberproject/database"
+	"myfiberproject/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Access tokens can be revoked one by one (logout), by session (the refresh token family they were
+// issued for) or all at once for a user (log out everywhere). Revocations are stored in MongoDB and
+// cached in memory. Lookups that find nothing are cached for revocationCacheTTL, which bounds how
+// long a revocation made by another instance of the API takes to apply here.
+
+const revocationCacheTTL = 30 * time.Second
+
+func revokedTokenCollection() *mongo.Collection {
+	return database.GetMongoClient().Database(database.GetDatabaseName()).Collection("revoked_tokens")
+}
+
+func revocationCacheKey(id string) string {
+	return "revocation:" + id
+}
+
+// RevokeToken revokes a single access token until it expires
+func RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	return storeRevocation(ctx, models.RevokedToken{ID: "jti:" + tokenID, ExpiresAt: expiresAt})
+}
+
+// RevokeSession revokes the access tokens issued for a session. expiresAt must be at least the
+// expiry of the last access token issued for it.
+func RevokeSession(ctx context.Context, sessionID string, expiresAt time.Time) error {
+	return storeRevocation(ctx, models.RevokedToken{ID: "session:" + sessionID, ExpiresAt: expiresAt})
+}
+
+// RevokeUserTokens revokes every access token issued to a user up to now
+func RevokeUserTokens(ctx context.Context, userID string, expiresAt time.Time) error {
+	now := time.Now()
+	return storeRevocation(ctx, models.RevokedToken{ID: "user:" + userID, RevokedBefore: &now, ExpiresAt: expiresAt})
+}
+
+func storeRevocation(ctx context.Context, revocation models.RevokedToken) error {
+	revocation.RevokedAt = time.Now()
+	_, err := revokedTokenCollection().ReplaceOne(ctx, bson.M{"_id": revocation.ID}, revocation, options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	config.CacheInstance.Set(revocationCacheKey(revocation.ID), &revocation, time.Until(revocation.ExpiresAt))
+	return nil
+}
+
+// isTokenRevoked checks the token, its session and its user against the revocation list
+func isTokenRevoked(claims *CustomClaims) (bool, error) {
+	ids := []string{"jti:" + claims.ID, "user:" + claims.UserID}
+	if claims.SessionID != "" {
+		ids = append(ids, "session:"+claims.SessionID)
+	}
+
+	revocations := map[string]*models.RevokedToken{}
+	var missing []string
+	for _, id := range ids {
+		if cached, found := config.CacheInstance.Get(revocationCacheKey(id)); found {
+			revocations[id] = cached.(*models.RevokedToken)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		cursor, err := revokedTokenCollection().Find(ctx, bson.M{"_id": bson.M{"$in": missing}})
+		if err != nil {
+			return false, err
+		}
+		var found []models.RevokedToken
+		if err := cursor.All(ctx, &found); err != nil {
+			return false, err
+		}
+
+		for i := range found {
+			revocations[found[i].ID] = &found[i]
+			config.CacheInstance.Set(revocationCacheKey(found[i].ID), &found[i], time.Until(found[i].ExpiresAt))
+		}
+		for _, id := range missing {
+			if _, ok := revocations[id]; !ok {
+				revocations[id] = nil
+				config.CacheInstance.Set(revocationCacheKey(id), (*models.RevokedToken)(nil), revocationCacheTTL)
+			}
+		}
+	}
+
+	for id, revocation := range revocations {
+		if revocation == nil || !revocation.ExpiresAt.After(time.Now()) {
+			continue
+		}
+		if id != "user:"+claims.UserID {
+			return true, nil
+		}
+		// User-wide revocations only apply to tokens issued before them; iat has second precision
+		if revocation.RevokedBefore == nil || claims.IssuedAt == nil || claims.IssuedAt.Unix() <= revocation.RevokedBefore.Unix() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
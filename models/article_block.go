@@ -0,0 +1,25 @@
+package models
+
+type BlockType string
+
+const (
+	ParagraphBlock BlockType = "paragraph"
+	HeadingBlock   BlockType = "heading"
+	ImageBlock     BlockType = "image"
+	QuoteBlock     BlockType = "quote"
+	EmbedBlock     BlockType = "embed"
+	CodeBlock      BlockType = "code"
+)
+
+// ArticleBlock is one typed element of a structured article body.
+// Which fields are used depends on the type of the block.
+type ArticleBlock struct {
+	Type     BlockType `bson:"type" json:"type" validate:"required,oneof=paragraph heading image quote embed code"`
+	Text     string    `bson:"text,omitempty" json:"text,omitempty"`         // paragraph, heading, quote and code
+	Level    int       `bson:"level,omitempty" json:"level,omitempty"`       // heading, from 1 to 6
+	URL      string    `bson:"url,omitempty" json:"url,omitempty"`           // image and embed
+	Alt      string    `bson:"alt,omitempty" json:"alt,omitempty"`           // image
+	Caption  string    `bson:"caption,omitempty" json:"caption,omitempty"`   // image and embed
+	Citation string    `bson:"citation,omitempty" json:"citation,omitempty"` // quote
+	Language string    `bson:"language,omitempty" json:"language,omitempty"` // code
+}
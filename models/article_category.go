@@ -7,8 +7,10 @@ import (
 )
 
 type ArticleCategory struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
-	Name      string             `bson:"name" json:"name" validate:"required"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	Name          string             `bson:"name" json:"name" validate:"required"`
+	Slug          string             `bson:"slug" json:"slug"`
+	PreviousSlugs []string           `bson:"previous_slugs,omitempty" json:"previous_slugs,omitempty"` // Old slugs that redirect to Slug
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
 }
@@ -6,14 +6,48 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+type ArticleStatus string
+
+const (
+	Draft     ArticleStatus = "draft"     // Only visible to administrators
+	Published ArticleStatus = "published" // Served by the public API
+)
+
+type ContentFormat string
+
+const (
+	MarkdownFormat ContentFormat = "markdown"
+	HTMLFormat     ContentFormat = "html"
+	PlainFormat    ContentFormat = "plain" // Default for articles created before formats were declared
+)
+
+type EnrichmentStatus string
+
+const (
+	EnrichmentPending EnrichmentStatus = "pending" // Queued for AI enrichment, or lost by a restart and requeued on startup
+	EnrichmentDone    EnrichmentStatus = "done"
+	EnrichmentFailed  EnrichmentStatus = "failed"
+)
+
 type ArticleContent struct {
 	ID                    primitive.ObjectID   `bson:"_id,omitempty" json:"_id,omitempty"`
 	Title                 string               `bson:"title" json:"title" validate:"required"`
+	Slug                  string               `bson:"slug" json:"slug"`
+	PreviousSlugs         []string             `bson:"previous_slugs,omitempty" json:"previous_slugs,omitempty"` // Old slugs that redirect to Slug
 	Excerpt               string               `bson:"excerpt" json:"excerpt"`
-	Content               string               `bson:"content" json:"content" validate:"required"`
-	Image                 string               `bson:"image" json:"image"`                                   // URL or path to the generated image
-	ArticleCategories     []primitive.ObjectID `bson:"article_categories" json:"article_categories"`         // References ArticleCategory
-	RecommendedCategories []string             `bson:"recommended_categories" json:"recommended_categories"` // Generated by Gemini
+	Content               string               `bson:"content" json:"content" validate:"required_without=Blocks"`
+	Blocks                []ArticleBlock       `bson:"blocks,omitempty" json:"blocks,omitempty" validate:"omitempty,dive"` // Optional structured body, Content is derived from it
+	ContentFormat         ContentFormat        `bson:"content_format" json:"content_format" validate:"omitempty,oneof=markdown html plain"`
+	ContentHTML           string               `bson:"-" json:"content_html"`                                          // Sanitized HTML rendered from Content on read
+	Image                 string               `bson:"image" json:"image"`                                             // URL or path to the generated image
+	ArticleCategories     []primitive.ObjectID `bson:"article_categories" json:"article_categories"`                   // References ArticleCategory
+	Tags                  []string             `bson:"tags" json:"tags"`                                               // Names of free-form tags, see Tag
+	RecommendedCategories []string             `bson:"recommended_categories" json:"recommended_categories"`           // Generated by Gemini
+	RecommendedTags       []string             `bson:"recommended_tags" json:"recommended_tags"`                       // Generated by OpenAI
+	EnrichmentStatus      EnrichmentStatus     `bson:"enrichment_status,omitempty" json:"enrichment_status,omitempty"` // Empty for articles created before enrichment was queued
+	Status                ArticleStatus        `bson:"status" json:"status" validate:"omitempty,oneof=draft published"`
+	PublishedAt           *time.Time           `bson:"published_at,omitempty" json:"published_at,omitempty"`
+	Version               int64                `bson:"version" json:"version"` // Incremented on every write, sent as the ETag
 	CreatedAt             time.Time            `bson:"created_at" json:"created_at"`
 	UpdatedAt             time.Time            `bson:"updated_at" json:"updated_at"`
 }
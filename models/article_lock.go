@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ArticleLock is an advisory lock held by the user editing an article. It lapses unless the
+// holder renews it with a heartbeat before ExpiresAt.
+type ArticleLock struct {
+	ID          primitive.ObjectID `bson:"_id" json:"article_id"` // ID of the locked article
+	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
+	UserName    string             `bson:"user_name" json:"user_name"`
+	AcquiredAt  time.Time          `bson:"acquired_at" json:"acquired_at"`
+	HeartbeatAt time.Time          `bson:"heartbeat_at" json:"heartbeat_at"`
+	ExpiresAt   time.Time          `bson:"expires_at" json:"expires_at"` // Removed by a TTL index once expired
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditAction names an event recorded in the audit log
+type AuditAction string
+
+const (
+	AuditUserApproved       AuditAction = "user.approved"
+	AuditInvitationCreated  AuditAction = "invitation.created"
+	AuditInvitationResent   AuditAction = "invitation.resent"
+	AuditInvitationRevoked  AuditAction = "invitation.revoked"
+	AuditInvitationAccepted AuditAction = "invitation.accepted"
+
+	AuditMFAEnabled               AuditAction = "mfa.enabled"
+	AuditMFADisabled              AuditAction = "mfa.disabled"
+	AuditMFAReset                 AuditAction = "mfa.reset"
+	AuditRecoveryCodeUsed         AuditAction = "mfa.recovery_code_used"
+	AuditRecoveryCodesRegenerated AuditAction = "mfa.recovery_codes_regenerated"
+
+	AuditPasskeyRegistered   AuditAction = "passkey.registered"
+	AuditPasskeyDeleted      AuditAction = "passkey.deleted"
+	AuditPasskeyCloneWarning AuditAction = "passkey.clone_warning"
+
+	AuditUserProvisioned AuditAction = "user.provisioned"
+	AuditUserRoleSynced  AuditAction = "user.role_synced"
+	AuditUserOIDCLinked  AuditAction = "user.oidc_linked"
+)
+
+// AuditLog records a security-relevant action and who performed it
+type AuditLog struct {
+	ID        primitive.ObjectID     `bson:"_id" json:"_id"`
+	Action    AuditAction            `bson:"action" json:"action"`
+	ActorID   *primitive.ObjectID    `bson:"actor_id,omitempty" json:"actor_id,omitempty"`   // User who performed the action, if any
+	TargetID  *primitive.ObjectID    `bson:"target_id,omitempty" json:"target_id,omitempty"` // User or document the action applies to
+	Details   map[string]interface{} `bson:"details,omitempty" json:"details,omitempty"`
+	IP        string                 `bson:"ip,omitempty" json:"ip,omitempty"`
+	CreatedAt time.Time              `bson:"created_at" json:"created_at"`
+}
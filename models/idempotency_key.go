@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// IdempotencyKey records the response to a request sent with an Idempotency-Key header, so that a
+// retry of the same request gets the same response instead of repeating the work
+type IdempotencyKey struct {
+	ID          string    `bson:"_id"`          // Hash of the user, route and key
+	RequestHash string    `bson:"request_hash"` // Hash of the request body, to detect reuse with another request
+	StatusCode  int       `bson:"status_code"`  // 0 while the first request is still running
+	LeaseID     string    `bson:"lease_id"`     // Identifies the request that holds the key while it runs
+	LockedUntil time.Time `bson:"locked_until"` // Another request may take the key over after this, if no response was stored
+	ContentType string    `bson:"content_type,omitempty"`
+	Body        []byte    `bson:"body,omitempty"`
+	CreatedAt   time.Time `bson:"created_at"`
+	ExpiresAt   time.Time `bson:"expires_at"` // Removed by a TTL index once expired
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type InvitationStatus string
+
+const (
+	InvitationPending  InvitationStatus = "pending"
+	InvitationAccepted InvitationStatus = "accepted"
+	InvitationRevoked  InvitationStatus = "revoked"
+)
+
+// Invitation lets an administrator onboard a colleague with a pre-assigned role. Only the hash of
+// the token is stored; the token itself is sent to the invitee by email.
+type Invitation struct {
+	ID         primitive.ObjectID  `bson:"_id" json:"_id"`
+	Email      string              `bson:"email" json:"email"`
+	FullName   string              `bson:"full_name,omitempty" json:"full_name,omitempty"`
+	Role       Role                `bson:"role" json:"role"`
+	Status     InvitationStatus    `bson:"status" json:"status"`
+	TokenHash  string              `bson:"token_hash" json:"-"`
+	InvitedBy  primitive.ObjectID  `bson:"invited_by" json:"invited_by"`
+	UserID     *primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"` // UserID: the user created on acceptance
+	SentAt     time.Time           `bson:"sent_at" json:"sent_at"`
+	AcceptedAt *time.Time          `bson:"accepted_at,omitempty" json:"accepted_at,omitempty"`
+	RevokedAt  *time.Time          `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time           `bson:"created_at" json:"created_at"`
+	ExpiresAt  time.Time           `bson:"expires_at" json:"expires_at"` // ExpiresAt: kept for the record, checked on acceptance
+}
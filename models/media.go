@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Media is an uploaded or imported file kept in media storage
+type Media struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	FileName    string             `bson:"file_name" json:"file_name"` // Original file name
+	ContentType string             `bson:"content_type" json:"content_type"`
+	Size        int64              `bson:"size" json:"size"`
+	Path        string             `bson:"path" json:"-"` // Location relative to MEDIA_DIR
+	URL         string             `bson:"url" json:"url"`
+	Source      string             `bson:"source,omitempty" json:"source,omitempty"` // Import file or URL the media came from
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
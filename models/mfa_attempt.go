@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MFAAttempts counts the second factor codes tried for a user, across challenges and server
+// instances. Too many attempts lock the user out of second factor checks for a while.
+type MFAAttempts struct {
+	UserID      primitive.ObjectID `bson:"_id"`
+	Attempts    int                `bson:"attempts"`
+	LockedUntil *time.Time         `bson:"locked_until,omitempty"`
+	ExpiresAt   time.Time          `bson:"expires_at"` // Removed by a TTL index, which resets the count
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OIDCState remembers a login sent to the identity provider until it returns to the callback. Only
+// the hashes of the state parameter and of the flow cookie are stored.
+type OIDCState struct {
+	ID           primitive.ObjectID  `bson:"_id"`
+	StateHash    string              `bson:"state_hash"`
+	BrowserHash  string              `bson:"browser_hash"` // Hash of the flow cookie set in the browser that started the login
+	Nonce        string              `bson:"nonce"`
+	CodeVerifier string              `bson:"code_verifier"` // PKCE verifier sent with the token request
+	RememberMe   bool                `bson:"remember_me"`
+	LinkUserID   *primitive.ObjectID `bson:"link_user_id,omitempty"` // Set when a logged-in user links their account instead of logging in
+	ExpiresAt    time.Time           `bson:"expires_at"`             // Removed by a TTL index once expired
+}
+
+// OIDCLoginCode hands a completed SSO login to the frontend. The callback redirects with the code,
+// and the frontend exchanges it once for session tokens, so tokens never appear in a URL.
+type OIDCLoginCode struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	CodeHash   string             `bson:"code_hash"`
+	UserID     primitive.ObjectID `bson:"user_id"`
+	RememberMe bool               `bson:"remember_me"`
+	MFA        bool               `bson:"mfa"`        // The identity provider reported a multi-factor login
+	ExpiresAt  time.Time          `bson:"expires_at"` // Removed by a TTL index once expired
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Passkey is a WebAuthn credential registered by a user
+type Passkey struct {
+	ID           primitive.ObjectID  `bson:"_id" json:"_id"`
+	UserID       primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	Name         string              `bson:"name" json:"name"`                   // Label chosen by the user, such as "Work laptop"
+	CredentialID []byte              `bson:"credential_id" json:"credential_id"` // Raw credential ID, unique across users
+	Credential   webauthn.Credential `bson:"credential" json:"-"`                // Public key and authenticator data
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+	LastUsedAt   *time.Time          `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+}
+
+// WebAuthnCeremony holds the challenge of a registration or login between its begin and finish
+// requests
+type WebAuthnCeremony struct {
+	ID         primitive.ObjectID   `bson:"_id"`
+	Purpose    string               `bson:"purpose"`           // "registration" or "login"
+	UserID     *primitive.ObjectID  `bson:"user_id,omitempty"` // Unset for passwordless logins, where the passkey names the user
+	Name       string               `bson:"name,omitempty"`    // Name of the passkey being registered
+	RememberMe bool                 `bson:"remember_me"`
+	Session    webauthn.SessionData `bson:"session"`
+	ExpiresAt  time.Time            `bson:"expires_at"` // Removed by a TTL index once expired
+}
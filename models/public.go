@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PublicCategory is the representation of an ArticleCategory served by the public API
+type PublicCategory struct {
+	ID   primitive.ObjectID `json:"_id"`
+	Name string             `json:"name"`
+	Slug string             `json:"slug"`
+}
+
+// PublicArticle is the representation of a published ArticleContent served by the public API.
+// Internal fields such as AI recommendations and slug history are left out, and so is the raw
+// content: HTML articles may hold anything their author or an import put in, so the public API
+// only serves the sanitized ContentHTML.
+type PublicArticle struct {
+	ID          primitive.ObjectID `json:"_id"`
+	Title       string             `json:"title"`
+	Slug        string             `json:"slug"`
+	Excerpt     string             `json:"excerpt"`
+	ContentHTML string             `json:"content_html"`
+	Blocks      []ArticleBlock     `json:"blocks,omitempty"`
+	Image       string             `json:"image"`
+	Categories  []PublicCategory   `json:"categories"`
+	Tags        []string           `json:"tags"`
+	PublishedAt *time.Time         `json:"published_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
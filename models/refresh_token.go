@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshToken is one link in a chain of rotating refresh tokens. Every refresh marks the presented
+// token as used and issues the next one in the same family; a family starts at login.
+type RefreshToken struct {
+	ID         primitive.ObjectID `bson:"_id" json:"-"`
+	TokenHash  string             `bson:"token_hash" json:"-"` // SHA-256 of the token, the token itself is never stored
+	FamilyID   primitive.ObjectID `bson:"family_id" json:"family_id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	RememberMe bool               `bson:"remember_me" json:"remember_me"` // Selects the longer refresh token lifetime
+	MFA        bool               `bson:"mfa" json:"mfa"`                 // The login was completed with a second factor
+	UsedAt     *time.Time         `bson:"used_at,omitempty" json:"used_at,omitempty"`
+	RevokedAt  *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt  time.Time          `bson:"expires_at" json:"expires_at"` // Removed by a TTL index once expired
+
+	// Session details, carried over from token to token so that the latest one describes the session
+	SessionStartedAt time.Time `bson:"session_started_at" json:"session_started_at"` // Login time of the family
+	IP               string    `bson:"ip" json:"ip"`                                 // Client address at the last refresh
+	UserAgent        string    `bson:"user_agent" json:"user_agent"`
+	Device           string    `bson:"device" json:"device"` // Readable summary of UserAgent
+}
+
+// Session is an active login of a user, described by the latest refresh token of its family
+type Session struct {
+	ID         primitive.ObjectID `json:"id"` // Refresh token family
+	UserID     primitive.ObjectID `json:"user_id"`
+	Device     string             `json:"device"`
+	IP         string             `json:"ip"`
+	UserAgent  string             `json:"user_agent"`
+	RememberMe bool               `json:"remember_me"`
+	MFA        bool               `json:"mfa"`
+	Current    bool               `json:"current"` // Whether the request was made from this session
+	LastSeenAt time.Time          `json:"last_seen_at"`
+	CreatedAt  time.Time          `json:"created_at"`
+	ExpiresAt  time.Time          `json:"expires_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RevokedToken is an entry of the access token revocation list. Entries only need to outlive the
+// access tokens they revoke, after which a TTL index removes them.
+type RevokedToken struct {
+	ID            string     `bson:"_id"`                      // "jti:<token ID>", "session:<session ID>" or "user:<user ID>"
+	RevokedBefore *time.Time `bson:"revoked_before,omitempty"` // For users: tokens issued at or before this time are revoked
+	RevokedAt     time.Time  `bson:"revoked_at"`
+	ExpiresAt     time.Time  `bson:"expires_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SitemapEntryKind string
+
+const (
+	SitemapArticle  SitemapEntryKind = "article"
+	SitemapCategory SitemapEntryKind = "category"
+)
+
+// SitemapEntry is one URL of the public sitemap, kept in sync as content changes
+type SitemapEntry struct {
+	ID      primitive.ObjectID `bson:"_id" json:"_id"` // ID of the referenced article or category
+	Kind    SitemapEntryKind   `bson:"kind" json:"kind"`
+	Loc     string             `bson:"loc" json:"loc"`
+	LastMod time.Time          `bson:"lastmod" json:"lastmod"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Tag struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	Name       string             `bson:"name" json:"name" validate:"required"`
+	Slug       string             `bson:"slug" json:"slug"`
+	UsageCount int64              `bson:"usage_count" json:"usage_count"` // Number of articles using the tag
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+}
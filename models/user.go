@@ -11,6 +11,11 @@ type Role string // Role: type for user role
 const ( // const: keyword to declare a constant
 	Administrator Role = "administrator" // Administrator: constant for "administrator"
 	Viewer        Role = "viewer"        // Viewer: constant for "viewer"
+	PO            Role = "po"            // PO: constant for "po"
+	IT            Role = "it"            // IT: constant for "it"
+	Comms         Role = "comms"         // Comms: constant for "comms"
+	HR            Role = "hr"            // HR: constant for "hr"
+	CMAS          Role = "cmas"          // CMAS: constant for "cmas"
 )
 
 type Status string // Status: type for user status
@@ -28,6 +33,16 @@ type User struct { // User: struct for the user
 	Role               Role               `json:"role" bson:"role" validate:"omitempty,oneof=administrator viewer po it comms hr cmas"` // omitempty allows default setting for the field
 	Status             Status             `json:"status" bson:"status" validate:"omitempty,oneof=approved pending"`                     // omitempty allows for default setting for the field
 	TermsAndConditions bool               `json:"terms_and_conditions" bson:"terms_and_conditions" validate:"required"`
+	EmailVerifiedAt    *time.Time         `json:"email_verified_at,omitempty" bson:"email_verified_at,omitempty"` // EmailVerifiedAt: set when the user opens the verification link
+	MFAEnabledAt       *time.Time         `json:"mfa_enabled_at,omitempty" bson:"mfa_enabled_at,omitempty"`       // MFAEnabledAt: set when TOTP enrollment is confirmed
+	TOTPSecret         string             `json:"-" bson:"totp_secret,omitempty"`                                 // TOTPSecret: encrypted, see handlers/mfa.go
+	TOTPPendingSecret  string             `json:"-" bson:"totp_pending_secret,omitempty"`                         // TOTPPendingSecret: enrollment waiting for its first code
+	TOTPLastStep       int64              `json:"-" bson:"totp_last_step,omitempty"`                              // TOTPLastStep: time step of the last accepted code, so codes are single-use
+	RecoveryCodes      []string           `json:"-" bson:"recovery_codes,omitempty"`                              // RecoveryCodes: SHA-256 hashes of the unused recovery codes
+	OIDCIssuer         string             `json:"oidc_issuer,omitempty" bson:"oidc_issuer,omitempty"`             // OIDCIssuer: identity provider the user signs in with
+	OIDCSubject        string             `json:"-" bson:"oidc_subject,omitempty"`                                // OIDCSubject: the user's ID at the identity provider
+	OIDCProvisioned    bool               `json:"-" bson:"oidc_provisioned,omitempty"`                            // OIDCProvisioned: the account was created by SSO, so its role follows the provider's groups
+	Version            int64              `json:"version" bson:"version"`                                         // Version: incremented on every write, sent as the ETag
 	CreatedAt          time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt          time.Time          `json:"updated_at" bson:"updated_at"`
 }
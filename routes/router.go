@@ -3,15 +3,30 @@
 package routes // routes: package for all routes
 
 import (
+	"myfiberproject/config"     // config: package for configuration
 	"myfiberproject/handlers"   // handlers: package for request handlers
 	"myfiberproject/middleware" // middleware: package for middleware
+	"time"                      // time: package for durations
 
-	"github.com/gofiber/fiber/v2" // fiber: package for building web applications
+	"github.com/gofiber/fiber/v2"                 // fiber: package for building web applications
+	"github.com/gofiber/fiber/v2/middleware/etag" // etag: package for ETag generation and validation
 )
 
 const (
 	BaseUserPath = "/users"
 	UserByIDPath = "/users/:id"
+
+	BaseArticleCategoryPath = "/article-category"
+	ArticleCategoryByIDPath = "/article-category/:id"
+
+	BaseArticleContentPath = "/article-content"
+	ArticleContentByIDPath = "/article-content/:id"
+
+	BaseTagPath = "/tags"
+	TagByIDPath = "/tags/:id"
+
+	PublicPath = "/public"
+	MediaPath  = "/media"
 )
 
 func SetupRoutes(app *fiber.App) { // SetupRoutes: function to set up all routes
@@ -19,8 +34,37 @@ func SetupRoutes(app *fiber.App) { // SetupRoutes: function to set up all routes
 	// User routes
 	app.Post("/signup", handlers.Signup)
 	app.Post("/login", handlers.Login)
-	app.Post("/logout", handlers.Logout)
+	app.Post("/logout", middleware.RequireAuth(), handlers.Logout)
+	app.Post("/logout/all", middleware.RequireAuth(), handlers.LogoutEverywhere)
+	app.Get("/sessions", middleware.RequireAuth(), handlers.GetMySessions)
+	app.Delete("/sessions/:sessionId", middleware.RequireAuth(), handlers.RevokeMySession)
+	app.Post("/token/refresh", handlers.RefreshToken)
 	app.Post("/forgot-password", handlers.ForgotPassword)
+	app.Post("/reset-password", handlers.ResetPassword)
+	app.Post("/verify-email", handlers.VerifyEmail)
+	app.Post("/verify-email/resend", handlers.ResendVerificationEmail)
+
+	// Two-factor authentication routes; RequireAuth so that users whose role requires MFA can enroll
+	app.Post("/login/mfa", handlers.VerifyLoginMFA)
+	app.Get("/mfa", middleware.RequireAuth(), handlers.GetMFAStatus)
+	app.Post("/mfa/totp", middleware.RequireAuth(), handlers.BeginTOTPEnrollment)
+	app.Post("/mfa/totp/confirm", middleware.RequireAuth(), handlers.ConfirmTOTPEnrollment)
+	app.Delete("/mfa/totp", middleware.RequireAuth(), handlers.DisableTOTP)
+	app.Post("/mfa/recovery-codes", middleware.RequireAuth(), handlers.RegenerateRecoveryCodes)
+
+	// Single sign-on routes
+	app.Get("/auth/oidc/login", handlers.BeginOIDCLogin)
+	app.Get("/auth/oidc/callback", handlers.OIDCCallback)
+	app.Post("/auth/oidc/token", handlers.ExchangeOIDCLoginCode)
+	app.Post("/auth/oidc/link", middleware.RequireAuth(), handlers.BeginOIDCLink)
+
+	// Passkey routes
+	app.Post("/login/passkey/begin", handlers.BeginPasskeyLogin)
+	app.Post("/login/passkey/finish", handlers.FinishPasskeyLogin)
+	app.Get("/passkeys", middleware.RequireAuth(), handlers.GetMyPasskeys)
+	app.Post("/passkeys/register/begin", middleware.RequireAuth(), handlers.BeginPasskeyRegistration)
+	app.Post("/passkeys/register/finish", middleware.RequireAuth(), handlers.FinishPasskeyRegistration)
+	app.Delete("/passkeys/:passkeyId", middleware.RequireAuth(), handlers.DeleteMyPasskey)
 
 	// Admin routes
 	app.Post("/seed-admin", handlers.SeedAdminHandler)
@@ -30,12 +74,73 @@ func SetupRoutes(app *fiber.App) { // SetupRoutes: function to set up all routes
 	app.Get(UserByIDPath, middleware.RequireRole([]string{"administrator"}, "approved"), handlers.GetSingleUser)
 	app.Put(UserByIDPath, middleware.RequireRole([]string{"administrator"}, "approved"), handlers.UpdateUser)
 	app.Delete(UserByIDPath, middleware.RequireRole([]string{"administrator"}, "approved"), handlers.DeleteUser)
+	app.Get(UserByIDPath+"/sessions", middleware.RequireRole([]string{"administrator"}, "approved"), handlers.GetUserSessions)
+	app.Delete(UserByIDPath+"/sessions", middleware.RequireRole([]string{"administrator"}, "approved"), handlers.RevokeAllUserSessions)
+	app.Delete(UserByIDPath+"/sessions/:sessionId", middleware.RequireRole([]string{"administrator"}, "approved"), handlers.RevokeUserSession)
+	app.Delete(UserByIDPath+"/mfa", middleware.RequireRole([]string{"administrator"}, "approved"), handlers.ResetUserMFA)
+
+	// Invitation routes
+	app.Get("/invitations/accept", handlers.GetInvitationByToken)
+	app.Post("/invitations/accept", handlers.AcceptInvitation)
+	app.Post("/invitations", middleware.RequireRole([]string{"administrator"}, "approved"), handlers.CreateInvitation)
+	app.Get("/invitations", middleware.RequireRole([]string{"administrator"}, "approved"), handlers.GetInvitations)
+	app.Post("/invitations/:id/resend", middleware.RequireRole([]string{"administrator"}, "approved"), handlers.ResendInvitation)
+	app.Delete("/invitations/:id", middleware.RequireRole([]string{"administrator"}, "approved"), handlers.RevokeInvitation)
+
+	// Audit log routes
+	app.Get("/audit-logs", middleware.RequireRole([]string{"administrator"}, "approved"), handlers.GetAuditLogs)
 
 	// Article category
-	app.Post("/article-category", middleware.RequireRole([]string{"administrator"}, "approved"), handlers.CreateArticleCategory)
-	app.Get("/article-category", middleware.RequireRole([]string{"administrator"}, "approved"), handlers.GetAllArticleCategory)
+	app.Post(BaseArticleCategoryPath, middleware.RequireRole([]string{"administrator"}, "approved"), handlers.CreateArticleCategory)
+	app.Get(BaseArticleCategoryPath, middleware.RequireRole([]string{"administrator"}, "approved"), handlers.GetAllArticleCategory)
+	app.Get(ArticleCategoryByIDPath, middleware.RequireRole([]string{"administrator"}, "approved"), handlers.GetArticleCategoryByID)
+	app.Put(ArticleCategoryByIDPath, middleware.RequireRole([]string{"administrator"}, "approved"), handlers.UpdateArticleCategory)
+	app.Delete(ArticleCategoryByIDPath, middleware.RequireRole([]string{"administrator"}, "approved"), handlers.DeleteArticleCategory)
+	app.Post(ArticleCategoryByIDPath+"/merge", middleware.RequireRole([]string{"administrator"}, "approved"), handlers.MergeArticleCategory)
+
+	// Article content; the POST routes that call OpenAI can be retried safely with an Idempotency-Key
+	idempotent := middleware.Idempotency(time.Duration(config.GetEnvInt("IDEMPOTENCY_KEY_TTL_HOURS", 24)) * time.Hour)
+	app.Post(BaseArticleContentPath, middleware.RequireRole([]string{"administrator"}, "approved"), idempotent, handlers.CreateArticleContent)
+	app.Post(BaseArticleContentPath+"/bulk", middleware.RequireRole([]string{"administrator"}, "approved"), idempotent, handlers.CreateArticleContentBulk)
+	app.Post(BaseArticleContentPath+"/import", middleware.RequireRole([]string{"administrator"}, "approved"), idempotent, handlers.ImportArticleContent)
+	app.Post(BaseArticleContentPath+"/import/wordpress", middleware.RequireRole([]string{"administrator"}, "approved"), idempotent, handlers.ImportWordPressContent)
+	app.Get(BaseArticleContentPath, middleware.RequireRole([]string{"administrator"}, "approved"), handlers.GetAllArticleContent)
+	app.Get(BaseArticleContentPath+"/export", middleware.RequireRole([]string{"administrator"}, "approved"), handlers.ExportArticleContent)
+	app.Get(ArticleContentByIDPath, middleware.RequireRole([]string{"administrator"}, "approved"), handlers.GetArticleContentByID)
+	app.Put(ArticleContentByIDPath, middleware.RequireRole([]string{"administrator"}, "approved"), handlers.UpdateArticleContent)
+
+	// Article edit locks
+	app.Get(ArticleContentByIDPath+"/lock", middleware.RequireRole([]string{"administrator"}, "approved"), handlers.GetArticleLock)
+	app.Post(ArticleContentByIDPath+"/lock", middleware.RequireRole([]string{"administrator"}, "approved"), handlers.AcquireArticleLock)
+	app.Put(ArticleContentByIDPath+"/lock", middleware.RequireRole([]string{"administrator"}, "approved"), handlers.RenewArticleLock)
+	app.Delete(ArticleContentByIDPath+"/lock", middleware.RequireRole([]string{"administrator"}, "approved"), handlers.ReleaseArticleLock)
+
+	// Tags
+	app.Get(BaseTagPath, middleware.RequireRole([]string{"administrator"}, "approved"), handlers.GetAllTags)
+	app.Get(BaseTagPath+"/autocomplete", middleware.RequireRole([]string{"administrator"}, "approved"), handlers.AutocompleteTags)
+	app.Put(TagByIDPath, middleware.RequireRole([]string{"administrator"}, "approved"), handlers.RenameTag)
+	app.Post(TagByIDPath+"/merge", middleware.RequireRole([]string{"administrator"}, "approved"), handlers.MergeTag)
+
+	// Media storage
+	app.Static(MediaPath, handlers.MediaDir())
+
+	// Sitemap
+	app.Get("/sitemap.xml", middleware.RequireRole([]string{"public"}, ""), handlers.GetSitemap)
+	app.Get("/sitemaps/sitemap-:page.xml", middleware.RequireRole([]string{"public"}, ""), handlers.GetSitemapPage)
+	app.Post("/sitemap/rebuild", middleware.RequireRole([]string{"administrator"}, "approved"), handlers.RebuildSitemap)
 
-	// Article content
-	app.Post("/article-content", middleware.RequireRole([]string{"administrator"}, "approved"), handlers.CreateArticleContent)
+	// Public read-only routes for the website, serving published content only
+	public := app.Group(PublicPath,
+		middleware.RequireRole([]string{"public"}, ""),
+		middleware.CacheControl(time.Duration(config.GetEnvInt("PUBLIC_CACHE_MAX_AGE", 300))*time.Second),
+		etag.New(),
+	)
+	public.Get("/articles", handlers.GetPublicArticles)
+	public.Get("/articles/:slug", handlers.GetPublicArticle)
+	public.Get("/categories", handlers.GetPublicCategories)
+	public.Get("/categories/:slug", handlers.GetPublicCategory)
 
+	// Syndication feeds: rss, atom or json
+	public.Get("/feed.:format", handlers.GetPublicFeed)
+	public.Get("/categories/:slug/feed.:format", handlers.GetPublicFeed)
 }